@@ -39,128 +39,389 @@ func init() {
 }
 
 var messageKeyToIndex = map[string]int{
-	"An error occured calculating an absolute path: %s":      7,
-	"An error occured when checking the path \"%s\":\n%s":    8,
-	"Failed to read the current working directory: %s":       10,
-	"No command is declared":                                 25,
-	"No configuration file could be found":                   11,
-	"No job and no service is declared in the configuration": 26,
-	"No name is declared":                                    22,
-	"No step is declared in the job \"%s\"":                  13,
+	"\"%s\" cannot need itself":                                                        102,
+	"\"%s\" needs \"%s\", which does not exist":                                        103,
+	"A circular dependency has been detected in the needs":                             104,
+	"An env_file has an empty path":                                                    86,
+	"An error occured calculating an absolute path: %s":                                16,
+	"An error occured when checking the path \"%s\":\n%s":                              17,
+	"Failed to read the current working directory: %s":                                 19,
+	"Keep the interface open and restart the job when its watched files change":        9,
+	"No check is declared":                                                             11,
+	"No command is declared":                                                           51,
+	"No configuration file could be found":                                             20,
+	"No job and no service is declared in the configuration":                           71,
+	"No name is declared":                                                              48,
+	"No step is declared in the job \"%s\"":                                            30,
+	"Parameter of the job, given as name=value. It can be repeated for each parameter": 7,
 	"Path to a configuration file. If left empty, it will recursively search in the parent directories for a tera.yml file": 5,
 	"Print version and exit": 2,
 	"Run a job":              4,
-	"Script management that rides the lighting.":                                1,
-	"Start the service management interface":                                    6,
-	"The configuration is invalid: %s":                                          32,
-	"The contents of the file \"%s\" could not be read: %s":                     33,
-	"The file could not be parsed from YAML: %s":                                31,
-	"The job #%d has no name declared":                                          27,
-	"The key of a service is not defined":                                       29,
-	"The path \"%s\" is a directory":                                            9,
-	"The run_after hooks are invalid: %s":                                       24,
-	"The run_before hooks are invalid: %s":                                      23,
-	"The service \"%s\" is invalid: %s":                                         30,
-	"The step \"%s\" in the job \"%s\" has invalid run_after hooks: %s":         17,
-	"The step \"%s\" in the job \"%s\" has invalid run_before hooks: %s":        16,
-	"The step \"%s\" in the job \"%s\" has no task declared":                    18,
-	"The step #%d in the job \"%s\" has no name declared":                       14,
-	"The task \"%s\" in the step \"%s\" in the job \"%s\" is invalid: %s":       20,
-	"The task #%d has no command declared":                                      12,
-	"The task #%d in the step \"%s\" in the job \"%s\" is invalid: %s":          21,
-	"There are multiple jobs named \"%s\"":                                      28,
-	"There are multiple steps named \"%s\" in the job \"%s\"":                   15,
-	"There are multiple tasks named \"%s\" in the step \"%s\"in the job \"%s\"": 19,
-	"tera version %s\n":                                                         3,
-	"unknown (built from source)":                                               0,
-	"💥 The command failed:":                                                     34,
+	"Run all the tasks, even the ones that are up to date with their sources": 8,
+	"Script management that rides the lighting.":                              1,
+	"Start the service management interface":                                  10,
+	"Stream the outputs of the tasks instead of opening the interactive interface. This is the default when the output is not a terminal": 6,
+	"The body regex is invalid: %s":                                           59,
+	"The cmd healthcheck has no command declared":                             63,
+	"The configuration is invalid: %s":                                        83,
+	"The contents of the file \"%s\" could not be read: %s":                   85,
+	"The contents of the included file \"%s\" could not be read: %s":          94,
+	"The default value of the parameter \"%s\" is invalid: %s":                112,
+	"The delays of the restart policy cannot be negative":                     68,
+	"The enum parameter \"%s\" has no values declared":                        110,
+	"The env file \"%s\" could not be read: %s":                               87,
+	"The env file \"%s\" is invalid: %s":                                      88,
+	"The environment variable name \"%s\" is invalid":                         12,
+	"The exit code %d is invalid":                                             24,
+	"The expected status %d is not a valid HTTP status":                       58,
+	"The failure threshold of the liveness probe cannot be negative":          66,
+	"The file \"%s\" is included recursively":                                 93,
+	"The file could not be parsed from YAML: %s":                              84,
+	"The file healthcheck has no path declared":                               62,
+	"The glob \"%s\" is invalid":                                              27,
+	"The global environment is invalid: %s":                                   72,
+	"The http healthcheck has no url declared":                                60,
+	"The include pattern \"%s\" is invalid: %s":                               91,
+	"The included file \"%s\" cannot declare a log_file":                      96,
+	"The included file \"%s\" does not exist":                                 92,
+	"The included file \"%s\" is invalid: %s":                                 95,
+	"The interval and the timeout of the %s healthcheck cannot be negative":   67,
+	"The interval and the timeout of the liveness probe cannot be negative":   65,
+	"The job \"%s\" has an invalid environment: %s":                           32,
+	"The job \"%s\" has invalid parameters: %s":                               31,
+	"The job \"%s\" has invalid run_after hooks: %s":                          33,
+	"The job \"%s\" has invalid watched files: %s":                            34,
+	"The job \"%s\" is declared both in \"%s\" and in \"%s\"":                 97,
+	"The job #%d has no name declared":                                        73,
+	"The job has no parameter named \"%s\"":                                   13,
+	"The key of a service is not defined":                                     75,
+	"The line %d is not a valid variable declaration":                         89,
+	"The liveness probe requires a port, http, file or cmd healthcheck":       64,
+	"The maximum number of restarts cannot be negative":                       70,
+	"The multiplier of the restart policy must be greater than or equal to 1": 69,
+	"The number of retries cannot be negative":                                52,
+	"The output regex is invalid: %s":                                         61,
+	"The output_matches regex is invalid: %s":                                 25,
+	"The output_not_matches regex is invalid: %s":                             26,
+	"The parameter \"%s\" declares values, but it is not an enum":             109,
+	"The parameter \"%s\" has an unknown type \"%s\"":                         111,
+	"The parameter #%d has an invalid name \"%s\"":                            107,
+	"The path \"%s\" is a directory":                                          18,
+	"The port %d is invalid":                                                  55,
+	"The retry delay cannot be negative":                                      53,
+	"The run_after hooks are invalid: %s":                                     50,
+	"The run_before hooks are invalid: %s":                                    49,
+	"The service \"%s\" declares a timeout or retries, the restarts of a service are configured with its restart policy": 80,
+	"The service \"%s\" declares conditions, which are only supported in the jobs":                                       78,
+	"The service \"%s\" declares needs, the dependencies between services are declared with dependencies":                77,
+	"The service \"%s\" declares sources, which are only supported in the jobs":                                          79,
+	"The service \"%s\" has an invalid healthcheck: %s":                                                                  81,
+	"The service \"%s\" has an invalid restart policy: %s":                                                               82,
+	"The service \"%s\" is declared both in \"%s\" and in \"%s\"":                                                        98,
+	"The service \"%s\" is invalid: %s":                                                                                  76,
+	"The skip_if condition is invalid: %s":                                                                               15,
+	"The step \"%s\" in the job \"%s\" has an invalid environment: %s":                                                   37,
+	"The step \"%s\" in the job \"%s\" has invalid run_after hooks: %s":                                                  41,
+	"The step \"%s\" in the job \"%s\" has invalid run_before hooks: %s":                                                 40,
+	"The step \"%s\" in the job \"%s\" has invalid watched files: %s":                                                    39,
+	"The step \"%s\" in the job \"%s\" has no task declared":                                                             42,
+	"The step \"%s\" in the job \"%s\" is invalid: %s":                                                                   38,
+	"The step #%d in the job \"%s\" has no name declared":                                                                35,
+	"The steps of the job \"%s\" have invalid needs: %s":                                                                 47,
+	"The stop signal \"%s\" is not supported":                                                                            21,
+	"The stop timeout cannot be negative":                                                                                22,
+	"The task \"%s\" in the step \"%s\" in the job \"%s\" is invalid: %s":                                                44,
+	"The task #%d has no command declared":                                                                               28,
+	"The task #%d in the step \"%s\" in the job \"%s\" is invalid: %s":                                                   45,
+	"The task #%d is invalid: %s":                                                                                        29,
+	"The task declares generated files, but no sources":                                                                  54,
+	"The tasks of the step \"%s\" in the job \"%s\" have invalid needs: %s":                                              46,
+	"The timeout cannot be negative":                                                                                     23,
+	"The url \"%s\" is invalid: %s":                                                                                      56,
+	"The url \"%s\" must use the http or https scheme":                                                                   57,
+	"The value \"%s\" of the parameter \"%s\" is not a boolean":                                                          105,
+	"The value \"%s\" of the parameter \"%s\" must be one of: %s":                                                        106,
+	"The value on line %d has no closing quote":                                                                          90,
+	"The variable \"%s\" on line %d must be a scalar value":                                                              101,
+	"The variable \"%s\" used on line %d is not defined":                                                                 99,
+	"The vars section on line %d must be a mapping":                                                                      100,
+	"The when condition is invalid: %s":                                                                                  14,
+	"There are multiple jobs named \"%s\"":                                                                               74,
+	"There are multiple parameters named \"%s\"":                                                                         108,
+	"There are multiple steps named \"%s\" in the job \"%s\"":                                                            36,
+	"There are multiple tasks named \"%s\" in the step \"%s\"in the job \"%s\"":                                          43,
+	"tera version %s\n":           3,
+	"unknown (built from source)": 0,
+	"💥 The command failed:":       113,
 }
 
-var enIndex = []uint32{ // 36 elements
+var enIndex = []uint32{ // 115 elements
 	// Entry 0 - 1F
 	0x00000000, 0x0000001c, 0x00000047, 0x0000005e,
-	0x00000076, 0x00000080, 0x000000f6, 0x0000011d,
-	0x00000152, 0x00000189, 0x000001a9, 0x000001dd,
-	0x00000202, 0x0000022a, 0x00000251, 0x00000289,
-	0x000002c3, 0x0000030b, 0x00000352, 0x0000038b,
-	0x000003d8, 0x00000422, 0x0000046b, 0x0000047f,
-	0x000004a7, 0x000004ce, 0x000004e5, 0x0000051c,
-	0x00000540, 0x00000566, 0x0000058a, 0x000005b0,
+	0x00000076, 0x00000080, 0x000000f6, 0x0000017a,
+	0x000001cb, 0x00000213, 0x0000025d, 0x00000284,
+	0x00000299, 0x000002ca, 0x000002f1, 0x00000316,
+	0x0000033e, 0x00000373, 0x000003aa, 0x000003ca,
+	0x000003fe, 0x00000423, 0x0000044c, 0x00000470,
+	0x0000048f, 0x000004ae, 0x000004d9, 0x00000508,
+	0x00000524, 0x0000054c, 0x0000056e, 0x00000595,
 	// Entry 20 - 3F
-	0x000005de, 0x00000602, 0x0000063c, 0x00000655,
-} // Size: 168 bytes
+	0x000005c3, 0x000005f5, 0x00000628, 0x00000659,
+	0x00000691, 0x000006cb, 0x00000711, 0x00000747,
+	0x0000078c, 0x000007d4, 0x0000081b, 0x00000854,
+	0x000008a1, 0x000008eb, 0x00000934, 0x0000097f,
+	0x000009b6, 0x000009ca, 0x000009f2, 0x00000a19,
+	0x00000a30, 0x00000a59, 0x00000a7c, 0x00000aae,
+	0x00000ac8, 0x00000aea, 0x00000b1c, 0x00000b51,
+	0x00000b72, 0x00000b9b, 0x00000bbe, 0x00000be8,
+	// Entry 40 - 5F
+	0x00000c14, 0x00000c56, 0x00000c9c, 0x00000cdb,
+	0x00000d24, 0x00000d58, 0x00000da0, 0x00000dd2,
+	0x00000e09, 0x00000e32, 0x00000e56, 0x00000e7c,
+	0x00000ea0, 0x00000ec6, 0x00000f2b, 0x00000f79,
+	0x00000fc4, 0x00001038, 0x0000106e, 0x000010a7,
+	0x000010cb, 0x000010f9, 0x00001133, 0x00001151,
+	0x0000117f, 0x000011a6, 0x000011d9, 0x00001206,
+	0x00001234, 0x0000125d, 0x00001286, 0x000012c9,
+	// Entry 60 - 7F
+	0x000012f5, 0x00001329, 0x00001364, 0x000013a3,
+	0x000013da, 0x0000140b, 0x00001445, 0x00001460,
+	0x0000148c, 0x000014c1, 0x000014fd, 0x0000153e,
+	0x0000156f, 0x0000159b, 0x000015d8, 0x0000160a,
+	0x0000163c, 0x00001679, 0x00001692,
+} // Size: 484 bytes
 
-const enData string = "" + // Size: 1621 bytes
+const enData string = "" + // Size: 5778 bytes
 	"\x02unknown (built from source)\x02Script management that rides the ligh" +
 	"ting.\x02Print version and exit\x04\x00\x01\x0a\x13\x02tera version %[1]" +
 	"s\x02Run a job\x02Path to a configuration file. If left empty, it will r" +
-	"ecursively search in the parent directories for a tera.yml file\x02Start" +
-	" the service management interface\x02An error occured calculating an abs" +
-	"olute path: %[1]s\x02An error occured when checking the path \x22%[1]s" +
-	"\x22:\x0a%[2]s\x02The path \x22%[1]s\x22 is a directory\x02Failed to rea" +
-	"d the current working directory: %[1]s\x02No configuration file could be" +
-	" found\x02The task #%[1]d has no command declared\x02No step is declared" +
-	" in the job \x22%[1]s\x22\x02The step #%[1]d in the job \x22%[2]s\x22 ha" +
-	"s no name declared\x02There are multiple steps named \x22%[1]s\x22 in th" +
-	"e job \x22%[2]s\x22\x02The step \x22%[1]s\x22 in the job \x22%[2]s\x22 h" +
-	"as invalid run_before hooks: %[3]s\x02The step \x22%[1]s\x22 in the job " +
-	"\x22%[2]s\x22 has invalid run_after hooks: %[3]s\x02The step \x22%[1]s" +
-	"\x22 in the job \x22%[2]s\x22 has no task declared\x02There are multiple" +
-	" tasks named \x22%[1]s\x22 in the step \x22%[2]s\x22in the job \x22%[3]s" +
-	"\x22\x02The task \x22%[1]s\x22 in the step \x22%[2]s\x22 in the job \x22" +
-	"%[3]s\x22 is invalid: %[4]s\x02The task #%[1]d in the step \x22%[2]s\x22" +
-	" in the job \x22%[3]s\x22 is invalid: %[4]s\x02No name is declared\x02Th" +
-	"e run_before hooks are invalid: %[1]s\x02The run_after hooks are invalid" +
-	": %[1]s\x02No command is declared\x02No job and no service is declared i" +
-	"n the configuration\x02The job #%[1]d has no name declared\x02There are " +
-	"multiple jobs named \x22%[1]s\x22\x02The key of a service is not defined" +
-	"\x02The service \x22%[1]s\x22 is invalid: %[2]s\x02The file could not be" +
-	" parsed from YAML: %[1]s\x02The configuration is invalid: %[1]s\x02The c" +
-	"ontents of the file \x22%[1]s\x22 could not be read: %[2]s\x02💥 The comm" +
-	"and failed:"
+	"ecursively search in the parent directories for a tera.yml file\x02Strea" +
+	"m the outputs of the tasks instead of opening the interactive interface." +
+	" This is the default when the output is not a terminal\x02Parameter of t" +
+	"he job, given as name=value. It can be repeated for each parameter\x02Ru" +
+	"n all the tasks, even the ones that are up to date with their sources" +
+	"\x02Keep the interface open and restart the job when its watched files c" +
+	"hange\x02Start the service management interface\x02No check is declared" +
+	"\x02The environment variable name \x22%[1]s\x22 is invalid\x02The job ha" +
+	"s no parameter named \x22%[1]s\x22\x02The when condition is invalid: %[1" +
+	"]s\x02The skip_if condition is invalid: %[1]s\x02An error occured calcul" +
+	"ating an absolute path: %[1]s\x02An error occured when checking the path" +
+	" \x22%[1]s\x22:\x0a%[2]s\x02The path \x22%[1]s\x22 is a directory\x02Fai" +
+	"led to read the current working directory: %[1]s\x02No configuration fil" +
+	"e could be found\x02The stop signal \x22%[1]s\x22 is not supported\x02Th" +
+	"e stop timeout cannot be negative\x02The timeout cannot be negative\x02T" +
+	"he exit code %[1]d is invalid\x02The output_matches regex is invalid: %[" +
+	"1]s\x02The output_not_matches regex is invalid: %[1]s\x02The glob \x22%[" +
+	"1]s\x22 is invalid\x02The task #%[1]d has no command declared\x02The tas" +
+	"k #%[1]d is invalid: %[2]s\x02No step is declared in the job \x22%[1]s" +
+	"\x22\x02The job \x22%[1]s\x22 has invalid parameters: %[2]s\x02The job " +
+	"\x22%[1]s\x22 has an invalid environment: %[2]s\x02The job \x22%[1]s\x22" +
+	" has invalid run_after hooks: %[2]s\x02The job \x22%[1]s\x22 has invalid" +
+	" watched files: %[2]s\x02The step #%[1]d in the job \x22%[2]s\x22 has no" +
+	" name declared\x02There are multiple steps named \x22%[1]s\x22 in the jo" +
+	"b \x22%[2]s\x22\x02The step \x22%[1]s\x22 in the job \x22%[2]s\x22 has a" +
+	"n invalid environment: %[3]s\x02The step \x22%[1]s\x22 in the job \x22%[" +
+	"2]s\x22 is invalid: %[3]s\x02The step \x22%[1]s\x22 in the job \x22%[2]s" +
+	"\x22 has invalid watched files: %[3]s\x02The step \x22%[1]s\x22 in the j" +
+	"ob \x22%[2]s\x22 has invalid run_before hooks: %[3]s\x02The step \x22%[1" +
+	"]s\x22 in the job \x22%[2]s\x22 has invalid run_after hooks: %[3]s\x02Th" +
+	"e step \x22%[1]s\x22 in the job \x22%[2]s\x22 has no task declared\x02Th" +
+	"ere are multiple tasks named \x22%[1]s\x22 in the step \x22%[2]s\x22in t" +
+	"he job \x22%[3]s\x22\x02The task \x22%[1]s\x22 in the step \x22%[2]s\x22" +
+	" in the job \x22%[3]s\x22 is invalid: %[4]s\x02The task #%[1]d in the st" +
+	"ep \x22%[2]s\x22 in the job \x22%[3]s\x22 is invalid: %[4]s\x02The tasks" +
+	" of the step \x22%[1]s\x22 in the job \x22%[2]s\x22 have invalid needs: " +
+	"%[3]s\x02The steps of the job \x22%[1]s\x22 have invalid needs: %[2]s" +
+	"\x02No name is declared\x02The run_before hooks are invalid: %[1]s\x02Th" +
+	"e run_after hooks are invalid: %[1]s\x02No command is declared\x02The nu" +
+	"mber of retries cannot be negative\x02The retry delay cannot be negative" +
+	"\x02The task declares generated files, but no sources\x02The port %[1]d " +
+	"is invalid\x02The url \x22%[1]s\x22 is invalid: %[2]s\x02The url \x22%[1" +
+	"]s\x22 must use the http or https scheme\x02The expected status %[1]d is" +
+	" not a valid HTTP status\x02The body regex is invalid: %[1]s\x02The http" +
+	" healthcheck has no url declared\x02The output regex is invalid: %[1]s" +
+	"\x02The file healthcheck has no path declared\x02The cmd healthcheck has" +
+	" no command declared\x02The liveness probe requires a port, http, file o" +
+	"r cmd healthcheck\x02The interval and the timeout of the liveness probe " +
+	"cannot be negative\x02The failure threshold of the liveness probe cannot" +
+	" be negative\x02The interval and the timeout of the %[1]s healthcheck ca" +
+	"nnot be negative\x02The delays of the restart policy cannot be negative" +
+	"\x02The multiplier of the restart policy must be greater than or equal t" +
+	"o 1\x02The maximum number of restarts cannot be negative\x02No job and n" +
+	"o service is declared in the configuration\x02The global environment is " +
+	"invalid: %[1]s\x02The job #%[1]d has no name declared\x02There are multi" +
+	"ple jobs named \x22%[1]s\x22\x02The key of a service is not defined\x02T" +
+	"he service \x22%[1]s\x22 is invalid: %[2]s\x02The service \x22%[1]s\x22 " +
+	"declares needs, the dependencies between services are declared with depe" +
+	"ndencies\x02The service \x22%[1]s\x22 declares conditions, which are onl" +
+	"y supported in the jobs\x02The service \x22%[1]s\x22 declares sources, w" +
+	"hich are only supported in the jobs\x02The service \x22%[1]s\x22 declare" +
+	"s a timeout or retries, the restarts of a service are configured with it" +
+	"s restart policy\x02The service \x22%[1]s\x22 has an invalid healthcheck" +
+	": %[2]s\x02The service \x22%[1]s\x22 has an invalid restart policy: %[2]" +
+	"s\x02The configuration is invalid: %[1]s\x02The file could not be parsed" +
+	" from YAML: %[1]s\x02The contents of the file \x22%[1]s\x22 could not be" +
+	" read: %[2]s\x02An env_file has an empty path\x02The env file \x22%[1]s" +
+	"\x22 could not be read: %[2]s\x02The env file \x22%[1]s\x22 is invalid: " +
+	"%[2]s\x02The line %[1]d is not a valid variable declaration\x02The value" +
+	" on line %[1]d has no closing quote\x02The include pattern \x22%[1]s\x22" +
+	" is invalid: %[2]s\x02The included file \x22%[1]s\x22 does not exist\x02" +
+	"The file \x22%[1]s\x22 is included recursively\x02The contents of the in" +
+	"cluded file \x22%[1]s\x22 could not be read: %[2]s\x02The included file " +
+	"\x22%[1]s\x22 is invalid: %[2]s\x02The included file \x22%[1]s\x22 canno" +
+	"t declare a log_file\x02The job \x22%[1]s\x22 is declared both in \x22%[" +
+	"2]s\x22 and in \x22%[3]s\x22\x02The service \x22%[1]s\x22 is declared bo" +
+	"th in \x22%[2]s\x22 and in \x22%[3]s\x22\x02The variable \x22%[1]s\x22 u" +
+	"sed on line %[2]d is not defined\x02The vars section on line %[1]d must " +
+	"be a mapping\x02The variable \x22%[1]s\x22 on line %[2]d must be a scala" +
+	"r value\x02\x22%[1]s\x22 cannot need itself\x02\x22%[1]s\x22 needs \x22%" +
+	"[2]s\x22, which does not exist\x02A circular dependency has been detecte" +
+	"d in the needs\x02The value \x22%[1]s\x22 of the parameter \x22%[2]s\x22" +
+	" is not a boolean\x02The value \x22%[1]s\x22 of the parameter \x22%[2]s" +
+	"\x22 must be one of: %[3]s\x02The parameter #%[1]d has an invalid name " +
+	"\x22%[2]s\x22\x02There are multiple parameters named \x22%[1]s\x22\x02Th" +
+	"e parameter \x22%[1]s\x22 declares values, but it is not an enum\x02The " +
+	"enum parameter \x22%[1]s\x22 has no values declared\x02The parameter " +
+	"\x22%[1]s\x22 has an unknown type \x22%[2]s\x22\x02The default value of " +
+	"the parameter \x22%[1]s\x22 is invalid: %[2]s\x02💥 The command failed:"
 
-var frIndex = []uint32{ // 36 elements
+var frIndex = []uint32{ // 115 elements
 	// Entry 0 - 1F
 	0x00000000, 0x0000002a, 0x00000058, 0x00000077,
-	0x0000008f, 0x000000b2, 0x0000014c, 0x00000176,
-	0x000001be, 0x0000020d, 0x00000236, 0x00000272,
-	0x000002a6, 0x000002cb, 0x000002fa, 0x00000327,
-	0x0000035e, 0x000003a8, 0x000003ec, 0x00000425,
-	0x0000046e, 0x000004b9, 0x00000503, 0x0000051d,
-	0x00000545, 0x0000056c, 0x0000058d, 0x000005c8,
-	0x000005e5, 0x00000607, 0x00000622, 0x0000064a,
+	0x0000008f, 0x000000b2, 0x0000014c, 0x000001ef,
+	0x00000252, 0x000002a1, 0x000002f7, 0x00000321,
+	0x00000347, 0x0000037f, 0x000003ab, 0x000003d2,
+	0x000003fc, 0x00000444, 0x00000493, 0x000004bc,
+	0x000004f8, 0x0000052c, 0x0000055b, 0x00000589,
+	0x000005bb, 0x000005e0, 0x0000060d, 0x0000063e,
+	0x0000065b, 0x00000680, 0x000006a6, 0x000006d5,
 	// Entry 20 - 3F
-	0x00000676, 0x000006a0, 0x000006da, 0x000006f8,
-} // Size: 168 bytes
+	0x00000708, 0x0000073b, 0x0000076f, 0x000007ab,
+	0x000007d8, 0x0000080f, 0x00000852, 0x00000886,
+	0x000008d2, 0x0000091c, 0x00000960, 0x00000999,
+	0x000009e2, 0x00000a2d, 0x00000a77, 0x00000ac2,
+	0x00000afb, 0x00000b15, 0x00000b3d, 0x00000b64,
+	0x00000b85, 0x00000bc2, 0x00000bfc, 0x00000c3b,
+	0x00000c56, 0x00000c79, 0x00000cae, 0x00000ce6,
+	0x00000d1c, 0x00000d3e, 0x00000d69, 0x00000d8f,
+	// Entry 40 - 5F
+	0x00000db6, 0x00000dfd, 0x00000e5b, 0x00000ea1,
+	0x00000efc, 0x00000f47, 0x00000f9d, 0x00000fdb,
+	0x00001016, 0x00001042, 0x0000105f, 0x00001081,
+	0x0000109c, 0x000010c4, 0x0000112e, 0x00001184,
+	0x000011d7, 0x00001279, 0x000012ae, 0x000012f2,
+	0x0000131c, 0x00001348, 0x00001382, 0x0000139f,
+	0x000013de, 0x00001416, 0x00001453, 0x0000148c,
+	0x000014be, 0x000014e5, 0x00001512, 0x00001553,
+	// Entry 60 - 7F
+	0x00001582, 0x000015be, 0x00001603, 0x0000164c,
+	0x0000168f, 0x000016cc, 0x00001711, 0x0000173c,
+	0x00001769, 0x000017a6, 0x000017e4, 0x00001825,
+	0x00001854, 0x0000187d, 0x000018c3, 0x000018f0,
+	0x00001920, 0x00001961, 0x0000197f,
+} // Size: 484 bytes
 
-const frData string = "" + // Size: 1784 bytes
+const frData string = "" + // Size: 6527 bytes
 	"\x02inconnue (compilation depuis les sources)\x02Lancez vos tâches plus " +
 	"vite que la lumière.\x02Afficher la version et quitter\x04\x00\x01\x0a" +
 	"\x13\x02tera version %[1]s\x02Exécuter une séquence de tâches\x02Chemin " +
 	"vers un fichier de configuration. Si aucune valeur n'est saisie, une rec" +
 	"herche dans les dossiers parents pour un fichier tera.yml sera exécutée." +
-	"\x02Lancer l'interface de gestion de services\x02Une erreur est survenue" +
-	" à la récupération d'un chemin absolu : %[1]s\x02Une erreur est survenue" +
-	" en listant les propriétés du fichier \x22%[1]s\x22 :\x0a%[2]s\x02Le che" +
-	"min \x22%[1]s\x22 pointe vers un dossier\x02Impossible de lire le répert" +
-	"oire de travail actuel : %[1]s\x02Aucun fichier de configuration n'a pu " +
-	"être trouvé\x02La tâche #%[1]d n'a pas de commande\x02Aucun step n'est " +
-	"déclaré dans le job \x22%[1]s\x22\x02Le step #%[1]d du job \x22%[2]s\x22" +
-	" n'a pas de nom\x02Plusieurs steps ont le nom \x22%[1]s\x22 dans le job " +
-	"\x22%[2]s\x22\x02Le step \x22%[1]s\x22 dans le job \x22%[2]s\x22 a un ho" +
-	"ok run_before invalide : %[3]s\x02Le step \x22%[1]s\x22 du job \x22%[2]s" +
-	"\x22 a un hook run_after invalide : %[3]s\x02Le step \x22%[1]s\x22 du jo" +
-	"b \x22%[2]s\x22 ne contient aucune tâche\x02Plusieurs tâches ont le nom " +
-	"\x22%[1]s\x22 dans le step \x22%[2]s\x22 du job \x22%[3]s\x22\x02La tâch" +
-	"e \x22%[1]s\x22 dans le step \x22%[2]s\x22 du job \x22%[3]s\x22 est inva" +
-	"lide : %[4]s\x02La tâche #%[1]d dans le step \x22%[2]s\x22 du job \x22%[" +
-	"3]s\x22 est invalide : %[4]s\x02Aucun nom n'est déclaré\x02Le hook run_b" +
-	"efore est invalide : %[1]s\x02Le hook run_after est invalide : %[1]s\x02" +
-	"Aucune commande n'est déclarée\x02Aucun job ou service n'est déclaré dan" +
-	"s la configuration\x02Le job #%[1]d n'a pas de nom\x02Plusieurs jobs ont" +
+	"\x02Afficher les sorties des tâches au fil de l'eau au lieu d'ouvrir l'i" +
+	"nterface interactive. C'est le comportement par défaut quand la sortie n" +
+	"'est pas un terminal\x02Paramètre du job, donné sous la forme nom=valeur" +
+	". Il peut être répété pour chaque paramètre\x02Exécuter toutes les tâche" +
+	"s, même celles qui sont à jour avec leurs sources\x02Garder l'interface " +
+	"ouverte et relancer le job quand ses fichiers surveillés changent\x02Lan" +
+	"cer l'interface de gestion de services\x02Aucune vérification n'est décl" +
+	"arée\x02Le nom de variable d'environnement \x22%[1]s\x22 est invalide" +
+	"\x02Le job n'a pas de paramètre nommé \x22%[1]s\x22\x02La condition when" +
+	" est invalide : %[1]s\x02La condition skip_if est invalide : %[1]s\x02Un" +
+	"e erreur est survenue à la récupération d'un chemin absolu : %[1]s\x02Un" +
+	"e erreur est survenue en listant les propriétés du fichier \x22%[1]s\x22" +
+	" :\x0a%[2]s\x02Le chemin \x22%[1]s\x22 pointe vers un dossier\x02Impossi" +
+	"ble de lire le répertoire de travail actuel : %[1]s\x02Aucun fichier de " +
+	"configuration n'a pu être trouvé\x02Le signal d'arrêt \x22%[1]s\x22 n'es" +
+	"t pas supporté\x02Le délai d'arrêt ne peut pas être négatif\x02Le délai " +
+	"d'expiration ne peut pas être négatif\x02Le code de sortie %[1]d est inv" +
+	"alide\x02La regex output_matches est invalide : %[1]s\x02La regex output" +
+	"_not_matches est invalide : %[1]s\x02Le glob \x22%[1]s\x22 est invalide" +
+	"\x02La tâche #%[1]d n'a pas de commande\x02La tâche #%[1]d est invalide " +
+	": %[2]s\x02Aucun step n'est déclaré dans le job \x22%[1]s\x22\x02Le job " +
+	"\x22%[1]s\x22 a des paramètres invalides : %[2]s\x02Le job \x22%[1]s\x22" +
+	" a un environnement invalide : %[2]s\x02Le job \x22%[1]s\x22 a un hook r" +
+	"un_after invalide : %[2]s\x02Le job \x22%[1]s\x22 a des fichiers surveil" +
+	"lés invalides : %[2]s\x02Le step #%[1]d du job \x22%[2]s\x22 n'a pas de " +
+	"nom\x02Plusieurs steps ont le nom \x22%[1]s\x22 dans le job \x22%[2]s" +
+	"\x22\x02Le step \x22%[1]s\x22 du job \x22%[2]s\x22 a un environnement in" +
+	"valide : %[3]s\x02Le step \x22%[1]s\x22 du job \x22%[2]s\x22 est invalid" +
+	"e : %[3]s\x02Le step \x22%[1]s\x22 du job \x22%[2]s\x22 a des fichiers s" +
+	"urveillés invalides : %[3]s\x02Le step \x22%[1]s\x22 dans le job \x22%[2" +
+	"]s\x22 a un hook run_before invalide : %[3]s\x02Le step \x22%[1]s\x22 du" +
+	" job \x22%[2]s\x22 a un hook run_after invalide : %[3]s\x02Le step \x22%" +
+	"[1]s\x22 du job \x22%[2]s\x22 ne contient aucune tâche\x02Plusieurs tâch" +
+	"es ont le nom \x22%[1]s\x22 dans le step \x22%[2]s\x22 du job \x22%[3]s" +
+	"\x22\x02La tâche \x22%[1]s\x22 dans le step \x22%[2]s\x22 du job \x22%[3" +
+	"]s\x22 est invalide : %[4]s\x02La tâche #%[1]d dans le step \x22%[2]s" +
+	"\x22 du job \x22%[3]s\x22 est invalide : %[4]s\x02Les tâches du step " +
+	"\x22%[1]s\x22 du job \x22%[2]s\x22 ont des needs invalides : %[3]s\x02Le" +
+	"s steps du job \x22%[1]s\x22 ont des needs invalides : %[2]s\x02Aucun no" +
+	"m n'est déclaré\x02Le hook run_before est invalide : %[1]s\x02Le hook ru" +
+	"n_after est invalide : %[1]s\x02Aucune commande n'est déclarée\x02Le nom" +
+	"bre de nouvelles tentatives ne peut pas être négatif\x02Le délai entre l" +
+	"es tentatives ne peut pas être négatif\x02La tâche déclare des fichiers " +
+	"générés, mais aucune source\x02Le port %[1]d est invalide\x02L'url \x22%" +
+	"[1]s\x22 est invalide : %[2]s\x02L'url \x22%[1]s\x22 doit utiliser le sc" +
+	"héma http ou https\x02Le statut attendu %[1]d n'est pas un statut HTTP v" +
+	"alide\x02La regex du corps de la réponse est invalide : %[1]s\x02Le heal" +
+	"thcheck http n'a pas d'url\x02La regex de la sortie est invalide : %[1]s" +
+	"\x02Le healthcheck file n'a pas de chemin\x02Le healthcheck cmd n'a pas " +
+	"de commande\x02La sonde de liveness nécessite un healthcheck port, http," +
+	" file ou cmd\x02L'intervalle et le délai d'expiration de la sonde de liv" +
+	"eness ne peuvent pas être négatifs\x02Le seuil d'échecs de la sonde de l" +
+	"iveness ne peut pas être négatif\x02L'intervalle et le délai d'expiratio" +
+	"n du healthcheck %[1]s ne peuvent pas être négatifs\x02Les délais de la " +
+	"politique de redémarrage ne peuvent pas être négatifs\x02Le multiplicate" +
+	"ur de la politique de redémarrage doit être supérieur ou égal à 1\x02Le " +
+	"nombre maximum de redémarrages ne peut pas être négatif\x02Aucun job ou " +
+	"service n'est déclaré dans la configuration\x02L'environnement global es" +
+	"t invalide : %[1]s\x02Le job #%[1]d n'a pas de nom\x02Plusieurs jobs ont" +
 	" le nom \x22%[1]s\x22\x02Un service n'a pas de clé\x02Le service \x22%[1" +
-	"]s\x22 est invalide : %[2]s\x02Le fichier YAML n'a pas pu être lu : %[1]" +
-	"s\x02La configuration n'est pas valide : %[1]s\x02Le contenu du fichier " +
-	"\x22%[1]s\x22 n'a pas pu être lu : %[2]s\x02💥 La commande a échoué :"
+	"]s\x22 est invalide : %[2]s\x02Le service \x22%[1]s\x22 déclare des need" +
+	"s, les dépendances entre services sont déclarées avec dependencies\x02Le" +
+	" service \x22%[1]s\x22 déclare des conditions, qui ne sont supportées qu" +
+	"e dans les jobs\x02Le service \x22%[1]s\x22 déclare des sources, qui ne " +
+	"sont supportées que dans les jobs\x02Le service \x22%[1]s\x22 déclare un" +
+	" délai d'expiration ou des nouvelles tentatives, les redémarrages d'un s" +
+	"ervice sont configurés avec sa politique de redémarrage\x02Le service " +
+	"\x22%[1]s\x22 a un healthcheck invalide : %[2]s\x02Le service \x22%[1]s" +
+	"\x22 a une politique de redémarrage invalide : %[2]s\x02La configuration" +
+	" n'est pas valide : %[1]s\x02Le fichier YAML n'a pas pu être lu : %[1]s" +
+	"\x02Le contenu du fichier \x22%[1]s\x22 n'a pas pu être lu : %[2]s\x02Un" +
+	" env_file a un chemin vide\x02Le fichier d'environnement \x22%[1]s\x22 n" +
+	"'a pas pu être lu : %[2]s\x02Le fichier d'environnement \x22%[1]s\x22 es" +
+	"t invalide : %[2]s\x02La ligne %[1]d n'est pas une déclaration de variab" +
+	"le valide\x02La valeur de la ligne %[1]d n'a pas de guillemet fermant" +
+	"\x02Le motif d'inclusion \x22%[1]s\x22 est invalide : %[2]s\x02Le fichie" +
+	"r inclus \x22%[1]s\x22 n'existe pas\x02Le fichier \x22%[1]s\x22 est incl" +
+	"us récursivement\x02Le contenu du fichier inclus \x22%[1]s\x22 n'a pas p" +
+	"u être lu : %[2]s\x02Le fichier inclus \x22%[1]s\x22 est invalide : %[2]" +
+	"s\x02Le fichier inclus \x22%[1]s\x22 ne peut pas déclarer de log_file" +
+	"\x02Le job \x22%[1]s\x22 est déclaré à la fois dans \x22%[2]s\x22 et dan" +
+	"s \x22%[3]s\x22\x02Le service \x22%[1]s\x22 est déclaré à la fois dans " +
+	"\x22%[2]s\x22 et dans \x22%[3]s\x22\x02La variable \x22%[1]s\x22 utilisé" +
+	"e à la ligne %[2]d n'est pas définie\x02La section vars de la ligne %[1]" +
+	"d doit être un dictionnaire\x02La variable \x22%[1]s\x22 de la ligne %[2" +
+	"]d doit être une valeur scalaire\x02\x22%[1]s\x22 ne peut pas dépendre d" +
+	"e lui-même\x02\x22%[1]s\x22 dépend de \x22%[2]s\x22, qui n'existe pas" +
+	"\x02Une dépendance circulaire a été détectée dans les needs\x02La valeur" +
+	" \x22%[1]s\x22 du paramètre \x22%[2]s\x22 n'est pas un booléen\x02La val" +
+	"eur \x22%[1]s\x22 du paramètre \x22%[2]s\x22 doit être parmi : %[3]s\x02" +
+	"Le paramètre #%[1]d a un nom invalide \x22%[2]s\x22\x02Plusieurs paramèt" +
+	"res ont le nom \x22%[1]s\x22\x02Le paramètre \x22%[1]s\x22 déclare des v" +
+	"aleurs, mais ce n'est pas un enum\x02Le paramètre enum \x22%[1]s\x22 n'a" +
+	" aucune valeur\x02Le paramètre \x22%[1]s\x22 a un type inconnu \x22%[2]s" +
+	"\x22\x02La valeur par défaut du paramètre \x22%[1]s\x22 est invalide : %" +
+	"[2]s\x02💥 La commande a échoué :"
 
-	// Total table size 3741 bytes (3KiB); checksum: D5B6733A
+	// Total table size 13273 bytes (12KiB); checksum: 6D5F69A0
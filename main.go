@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -20,6 +21,10 @@ var (
 	rootCmd *cobra.Command
 
 	confPath string
+	noTui    bool
+	params   []string
+	force    bool
+	watch    bool
 )
 
 func init() {
@@ -49,6 +54,9 @@ func init() {
 		Short: i18n.Sprintf("Run a job"),
 		Args:  cobra.MaximumNArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) >= 1 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
 			return jobexec.GetJobList(confPath), cobra.ShellCompDirectiveNoFileComp
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -57,11 +65,38 @@ func init() {
 				jobToRun = args[0]
 			}
 
-			return jobexec.ExecuteJob(confPath, jobToRun)
+			parsedParams, err := jobexec.ParseParamArgs(params)
+			if err != nil {
+				return err
+			}
+
+			err = jobexec.ExecuteJob(confPath, jobToRun, parsedParams, noTui, force, watch)
+			if errors.Is(err, jobexec.ErrJobFailed) {
+				os.Exit(1)
+			} else if errors.Is(err, jobexec.ErrJobCancelled) {
+				os.Exit(130)
+			}
+
+			return err
 		},
 	}
 	runCmd.Flags().StringVarP(&confPath, "config", "c", "", i18n.Sprintf("Path to a configuration file. If left empty, it will recursively search in the parent directories for a tera.yml file"))
+	runCmd.Flags().BoolVar(&noTui, "no-tui", false, i18n.Sprintf("Stream the outputs of the tasks instead of opening the interactive interface. This is the default when the output is not a terminal"))
+	runCmd.Flags().StringArrayVarP(&params, "param", "p", nil, i18n.Sprintf("Parameter of the job, given as name=value. It can be repeated for each parameter"))
+	runCmd.Flags().BoolVarP(&force, "force", "f", false, i18n.Sprintf("Run all the tasks, even the ones that are up to date with their sources"))
+	runCmd.Flags().BoolVarP(&watch, "watch", "w", false, i18n.Sprintf("Keep the interface open and restart the job when its watched files change"))
 	_ = runCmd.MarkFlagFilename("config", "yaml", "yml")
+	_ = runCmd.RegisterFlagCompletionFunc("param", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		jobToRun := ""
+		if len(args) >= 1 {
+			jobToRun = args[0]
+		}
+		if !strings.Contains(toComplete, "=") {
+			// The name of the parameter is followed by its value, without a space
+			return jobexec.GetParamCompletions(confPath, jobToRun, toComplete), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+		}
+		return jobexec.GetParamCompletions(confPath, jobToRun, toComplete), cobra.ShellCompDirectiveNoFileComp
+	})
 
 	rootCmd.AddCommand(runCmd)
 
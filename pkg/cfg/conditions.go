@@ -0,0 +1,73 @@
+package cfg
+
+import (
+	"slices"
+	"strings"
+)
+
+// Condition of a when or a skip_if: it holds if all the checks that are declared hold
+type ConditionConfig struct {
+	// Name of an environment variable that must be set and not empty, or "NAME=value" to check its value
+	Env string `yaml:"env,omitempty"`
+	// Path of a file that must exist, relative to the base path
+	File string `yaml:"file,omitempty"`
+	// Shell command that must succeed, run from the path (relative to the base path)
+	Cmd  string `yaml:"cmd,omitempty"`
+	Path string `yaml:"path,omitempty"`
+	// Values that the parameters of the job must have
+	Params map[string]string `yaml:"params,omitempty"`
+}
+
+// Conditions that decide whether a step or a task is run. It is run only if its when condition holds
+// and its skip_if condition does not. Otherwise it is skipped.
+type ConditionsConfig struct {
+	When   *ConditionConfig `yaml:"when,omitempty"`
+	SkipIf *ConditionConfig `yaml:"skip_if,omitempty"`
+}
+
+// Returns whether conditions are declared
+func (c ConditionsConfig) HasConditions() bool {
+	return c.When != nil || c.SkipIf != nil
+}
+
+func validateCondition(condition *ConditionConfig, params []ParamConfig) error {
+	if len(condition.Env) == 0 && len(condition.File) == 0 && len(condition.Cmd) == 0 && len(condition.Params) == 0 {
+		return newConfigError("No check is declared")
+	}
+
+	if len(condition.Env) > 0 {
+		name, _, _ := strings.Cut(condition.Env, "=")
+		if !envNameRegex.MatchString(name) {
+			return newConfigError("The environment variable name \"%s\" is invalid", name)
+		}
+	}
+
+	for name, value := range condition.Params {
+		paramIdx := slices.IndexFunc(params, func(param ParamConfig) bool {
+			return param.Name == name
+		})
+		if paramIdx < 0 {
+			return newConfigError("The job has no parameter named \"%s\"", name)
+		}
+		if _, err := params[paramIdx].NormalizeValue(value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateConditions(conditions ConditionsConfig, params []ParamConfig) error {
+	if conditions.When != nil {
+		if err := validateCondition(conditions.When, params); err != nil {
+			return newConfigError("The when condition is invalid: %s", err)
+		}
+	}
+	if conditions.SkipIf != nil {
+		if err := validateCondition(conditions.SkipIf, params); err != nil {
+			return newConfigError("The skip_if condition is invalid: %s", err)
+		}
+	}
+
+	return nil
+}
@@ -1,15 +1,23 @@
 package cfg
 
 import (
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type ConfigFile struct {
+	EnvConfig `yaml:"env_config,inline"`
+
 	BasePath    string                   `yaml:"-"`
 	LogFilePath string                   `yaml:"log_file"`
+	Vars        map[string]string        `yaml:"vars,omitempty"`
+	Include     []string                 `yaml:"include,omitempty"`
 	Jobs        []JobConfig              `yaml:"jobs,omitempty"`
 	Services    map[string]ServiceConfig `yaml:"services,omitempty"`
 }
@@ -20,47 +28,167 @@ type DependencyConfig struct {
 	WaitTargetStarted bool   `yaml:"wait_target_restarted"`
 }
 
+// Delay between two attempts of a healthcheck, and total duration after which it is failed
+type ProbeTimingConfig struct {
+	Interval time.Duration `yaml:"interval,omitempty"`
+	Timeout  time.Duration `yaml:"timeout,omitempty"`
+}
+
+type HttpHealthcheckConfig struct {
+	ProbeTimingConfig `yaml:"probe_timing_config,inline"`
+
+	Url            string `yaml:"url"`
+	Method         string `yaml:"method,omitempty"`
+	ExpectedStatus []int  `yaml:"expected_status,omitempty"`
+	BodyRegex      string `yaml:"body_regex,omitempty"`
+	Insecure       bool   `yaml:"insecure"`
+}
+
+// The file healthcheck passes once the file exists, the path is relative to the base path
+type FileHealthcheckConfig struct {
+	ProbeTimingConfig `yaml:"probe_timing_config,inline"`
+
+	Path string `yaml:"path"`
+}
+
+// The command healthcheck passes once the command exits successfully
+type CmdHealthcheckConfig struct {
+	ProbeTimingConfig `yaml:"probe_timing_config,inline"`
+
+	Cmd string `yaml:"cmd"`
+}
+
+type LivenessConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	Interval         time.Duration `yaml:"interval,omitempty"`
+	Timeout          time.Duration `yaml:"timeout,omitempty"`
+	FailureThreshold int           `yaml:"failure_threshold,omitempty"`
+	Restart          bool          `yaml:"restart"`
+}
+
+// The port and output healthchecks use the default timing, the other ones declare their own
 type HealthcheckConfig struct {
-	Port int `yaml:"port"`
-	// File       string `yaml:"file"`
-	// OutputText string `yaml:"output_text"`
+	Port        int                   `yaml:"port"`
+	Http        HttpHealthcheckConfig `yaml:"http"`
+	OutputRegex string                `yaml:"output_regex,omitempty"`
+	File        FileHealthcheckConfig `yaml:"file"`
+	Cmd         CmdHealthcheckConfig  `yaml:"cmd"`
+
+	// Keep probing the service once it has started, with its own timing
+	Liveness LivenessConfig `yaml:"liveness"`
+}
+
+type RestartPolicyConfig struct {
+	InitialDelay time.Duration `yaml:"initial_delay"`
+	Multiplier   float64       `yaml:"multiplier"`
+	MaxDelay     time.Duration `yaml:"max_delay"`
+	MaxRestarts  int           `yaml:"max_restarts"`
+	Window       time.Duration `yaml:"window"`
 }
 
 type ServiceConfig struct {
 	TaskConfig `yaml:"task_config,inline"`
 
-	Healthcheck  HealthcheckConfig  `yaml:"healthcheck"`
-	AutoRestart  bool               `yaml:"auto_restart"`
-	Dependencies []DependencyConfig `yaml:"dependencies"`
+	Healthcheck   HealthcheckConfig   `yaml:"healthcheck"`
+	AutoRestart   bool                `yaml:"auto_restart"`
+	RestartPolicy RestartPolicyConfig `yaml:"restart_policy"`
+	Dependencies  []DependencyConfig  `yaml:"dependencies"`
 
 	OpenTarget string `yaml:"open_target"`
+
+	// Command that stops the service, for the services that are not stopped with a signal
+	StopCmd string `yaml:"stop_cmd,omitempty"`
+
+	// Environment declared globally in the included file that declared the service
+	FileEnv EnvConfig `yaml:"-"`
 }
 
 type JobConfig struct {
-	Name     string       `yaml:"name"`
-	Steps    []StepConfig `yaml:"steps"`
-	RunAfter []CmdConfig  `yaml:"run_after,omitempty"`
+	EnvConfig `yaml:"env_config,inline"`
+
+	Name        string        `yaml:"name"`
+	Description string        `yaml:"description,omitempty"`
+	Params      []ParamConfig `yaml:"params,omitempty"`
+	Steps       []StepConfig  `yaml:"steps"`
+	RunAfter    []CmdConfig   `yaml:"run_after,omitempty"`
+
+	// Keep running the steps whose needs are successful after a step failed, instead of starting no new step
+	KeepGoing bool `yaml:"keep_going,omitempty"`
+
+	// Globs of the files that restart the whole job when they change, in watch mode
+	Watch []string `yaml:"watch,omitempty"`
+
+	// Environment declared globally in the included file that declared the job
+	FileEnv EnvConfig `yaml:"-"`
 }
 
 type StepConfig struct {
+	EnvConfig        `yaml:"env_config,inline"`
+	ConditionsConfig `yaml:"conditions_config,inline"`
+
 	Name      string       `yaml:"name"`
+	Needs     []string     `yaml:"needs,omitempty"`
 	Tasks     []TaskConfig `yaml:"tasks"`
 	RunBefore []CmdConfig  `yaml:"run_before,omitempty"`
 	RunAfter  []CmdConfig  `yaml:"run_after,omitempty"`
+
+	// Cancel the other tasks of the step as soon as one of them fails
+	FailFast bool `yaml:"fail_fast,omitempty"`
+
+	// Globs of the files that restart the job from this step when they change, in watch mode.
+	// The sources of the tasks of the step are watched too.
+	Watch []string `yaml:"watch,omitempty"`
 }
 
 type TaskConfig struct {
-	CmdConfig `yaml:"cmd_config,inline"`
+	CmdConfig        `yaml:"cmd_config,inline"`
+	ConditionsConfig `yaml:"conditions_config,inline"`
 
 	Name      string      `yaml:"name"`
+	Needs     []string    `yaml:"needs,omitempty"`
 	RunBefore []CmdConfig `yaml:"run_before,omitempty"`
 	RunAfter  []CmdConfig `yaml:"run_after,omitempty"`
+
+	// A failure of the task is displayed, but it does not fail the step
+	ContinueOnError bool `yaml:"continue_on_error,omitempty"`
+
+	// Number of times the command is run again when it fails, waiting for the retry delay between the attempts
+	Retries    int           `yaml:"retries,omitempty"`
+	RetryDelay time.Duration `yaml:"retry_delay,omitempty"`
+
+	// Globs of the files read and written by the task, relative to the base path. The task is skipped
+	// when its sources and its configuration did not change since its last success, and the generated files exist.
+	Sources   []string `yaml:"sources,omitempty"`
+	Generates []string `yaml:"generates,omitempty"`
 }
 
 type CmdConfig struct {
+	EnvConfig `yaml:"env_config,inline"`
+
 	Cmd  string `yaml:"cmd"`
 	Path string `yaml:"path,omitempty"`
-	// TODO: add a FailedWhen: a template calculated with the exit code, the stdout and stderr
+
+	// Signal sent to the processes when the command is stopped, before they are killed once the timeout is over
+	StopSignal  string        `yaml:"stop_signal,omitempty"`
+	StopTimeout time.Duration `yaml:"stop_timeout,omitempty"`
+
+	// The command is stopped and fails if it is still running after this duration
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// Run the command in a pseudo-terminal, for the tools that disable their colors and progress displays without one
+	Tty bool `yaml:"tty,omitempty"`
+
+	// Exit codes for which the command is successful, only 0 if none is declared
+	SuccessExitCodes []int `yaml:"success_exit_codes,omitempty"`
+	// Conditions on the output that make the command fail, whatever its exit code
+	FailedWhen FailedWhenConfig `yaml:"failed_when,omitempty"`
+}
+
+type FailedWhenConfig struct {
+	// The command fails if its output matches this regex
+	OutputMatches string `yaml:"output_matches,omitempty"`
+	// The command fails if its output does not match this regex
+	OutputNotMatches string `yaml:"output_not_matches,omitempty"`
 }
 
 // findConfig tries to find a configuration file. If no path is given in argument, it tries to find a tera.yml file in the parent directories of the current working directory.
@@ -108,11 +236,54 @@ func findConfig(givenPath string) (ret string, err error) {
 	return "", newConfigError("No configuration file could be found")
 }
 
+func validateCmdSettings(cmd CmdConfig) error {
+	if err := validateEnv(cmd.EnvConfig); err != nil {
+		return err
+	}
+	if len(cmd.StopSignal) > 0 {
+		if _, ok := ParseSignal(cmd.StopSignal); !ok {
+			return newConfigError("The stop signal \"%s\" is not supported", cmd.StopSignal)
+		}
+	}
+	if cmd.StopTimeout < 0 {
+		return newConfigError("The stop timeout cannot be negative")
+	}
+	if cmd.Timeout < 0 {
+		return newConfigError("The timeout cannot be negative")
+	}
+	for _, exitCode := range cmd.SuccessExitCodes {
+		if exitCode < 0 || exitCode > 255 {
+			return newConfigError("The exit code %d is invalid", exitCode)
+		}
+	}
+	if _, err := regexp.Compile(cmd.FailedWhen.OutputMatches); err != nil {
+		return newConfigError("The output_matches regex is invalid: %s", err)
+	}
+	if _, err := regexp.Compile(cmd.FailedWhen.OutputNotMatches); err != nil {
+		return newConfigError("The output_not_matches regex is invalid: %s", err)
+	}
+
+	return nil
+}
+
+func validateGlobs(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return newConfigError("The glob \"%s\" is invalid", pattern)
+		}
+	}
+
+	return nil
+}
+
 func validateCommands(configs []CmdConfig) error {
 	for cmdIdx, cmd := range configs {
 		if len(cmd.Cmd) == 0 {
 			return newConfigError("The task #%d has no command declared", cmdIdx)
 		}
+		if err := validateCmdSettings(cmd); err != nil {
+			return newConfigError("The task #%d is invalid: %s", cmdIdx, err)
+		}
 	}
 
 	return nil
@@ -123,6 +294,22 @@ func validateJobConfig(job *JobConfig) error {
 		return newConfigError("No step is declared in the job \"%s\"", job.Name)
 	}
 
+	if err := validateParams(job.Params); err != nil {
+		return newConfigError("The job \"%s\" has invalid parameters: %s", job.Name, err)
+	}
+
+	if err := validateEnv(job.EnvConfig); err != nil {
+		return newConfigError("The job \"%s\" has an invalid environment: %s", job.Name, err)
+	}
+
+	if err := validateCommands(job.RunAfter); err != nil {
+		return newConfigError("The job \"%s\" has invalid run_after hooks: %s", job.Name, err)
+	}
+
+	if err := validateGlobs(job.Watch); err != nil {
+		return newConfigError("The job \"%s\" has invalid watched files: %s", job.Name, err)
+	}
+
 	stepNames := make(map[string]bool)
 	for stepIdx, step := range job.Steps {
 		if len(step.Name) == 0 {
@@ -135,6 +322,18 @@ func validateJobConfig(job *JobConfig) error {
 		}
 		stepNames[step.Name] = true
 
+		if err := validateEnv(step.EnvConfig); err != nil {
+			return newConfigError("The step \"%s\" in the job \"%s\" has an invalid environment: %s", step.Name, job.Name, err)
+		}
+
+		if err := validateConditions(step.ConditionsConfig, job.Params); err != nil {
+			return newConfigError("The step \"%s\" in the job \"%s\" is invalid: %s", step.Name, job.Name, err)
+		}
+
+		if err := validateGlobs(step.Watch); err != nil {
+			return newConfigError("The step \"%s\" in the job \"%s\" has invalid watched files: %s", step.Name, job.Name, err)
+		}
+
 		// Check the hooks
 		if err := validateCommands(step.RunBefore); err != nil {
 			return newConfigError("The step \"%s\" in the job \"%s\" has invalid run_before hooks: %s", step.Name, job.Name, err)
@@ -160,9 +359,34 @@ func validateJobConfig(job *JobConfig) error {
 				}
 				return newConfigError("The task #%d in the step \"%s\" in the job \"%s\" is invalid: %s", taskIdx, step.Name, job.Name, err)
 			}
+			if err := validateConditions(task.ConditionsConfig, job.Params); err != nil {
+				return newConfigError("The task \"%s\" in the step \"%s\" in the job \"%s\" is invalid: %s", task.Name, step.Name, job.Name, err)
+			}
+		}
+
+		// Check the needs of the tasks, which reference the other tasks of the step
+		taskNeedNames := make([]string, len(step.Tasks))
+		taskNeeds := make([][]string, len(step.Tasks))
+		for taskIdx, task := range step.Tasks {
+			taskNeedNames[taskIdx] = task.Name
+			taskNeeds[taskIdx] = task.Needs
+		}
+		if err := validateNeeds(taskNeedNames, taskNeeds); err != nil {
+			return newConfigError("The tasks of the step \"%s\" in the job \"%s\" have invalid needs: %s", step.Name, job.Name, err)
 		}
 	}
 
+	// Check the needs of the steps
+	stepNeedNames := make([]string, len(job.Steps))
+	stepNeeds := make([][]string, len(job.Steps))
+	for stepIdx, step := range job.Steps {
+		stepNeedNames[stepIdx] = step.Name
+		stepNeeds[stepIdx] = step.Needs
+	}
+	if err := validateNeeds(stepNeedNames, stepNeeds); err != nil {
+		return newConfigError("The steps of the job \"%s\" have invalid needs: %s", job.Name, err)
+	}
+
 	return nil
 }
 
@@ -180,6 +404,104 @@ func validateTaskConfig(task TaskConfig) error {
 	if len(task.Cmd) == 0 {
 		return newConfigError("No command is declared")
 	}
+	if err := validateCmdSettings(task.CmdConfig); err != nil {
+		return err
+	}
+	if task.Retries < 0 {
+		return newConfigError("The number of retries cannot be negative")
+	}
+	if task.RetryDelay < 0 {
+		return newConfigError("The retry delay cannot be negative")
+	}
+	if err := validateGlobs(slices.Concat(task.Sources, task.Generates)); err != nil {
+		return err
+	}
+	if len(task.Generates) > 0 && len(task.Sources) == 0 {
+		return newConfigError("The task declares generated files, but no sources")
+	}
+
+	return nil
+}
+
+func validateHealthcheck(healthcheck HealthcheckConfig) error {
+	if healthcheck.Port < 0 || healthcheck.Port > 65535 {
+		return newConfigError("The port %d is invalid", healthcheck.Port)
+	}
+
+	if len(healthcheck.Http.Url) > 0 {
+		parsedUrl, err := url.Parse(healthcheck.Http.Url)
+		if err != nil {
+			return newConfigError("The url \"%s\" is invalid: %s", healthcheck.Http.Url, err)
+		}
+		if parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https" {
+			return newConfigError("The url \"%s\" must use the http or https scheme", healthcheck.Http.Url)
+		}
+		for _, status := range healthcheck.Http.ExpectedStatus {
+			if status < 100 || status > 599 {
+				return newConfigError("The expected status %d is not a valid HTTP status", status)
+			}
+		}
+		if _, err := regexp.Compile(healthcheck.Http.BodyRegex); err != nil {
+			return newConfigError("The body regex is invalid: %s", err)
+		}
+	} else if len(healthcheck.Http.Method) > 0 || len(healthcheck.Http.ExpectedStatus) > 0 || len(healthcheck.Http.BodyRegex) > 0 || healthcheck.Http.ProbeTimingConfig != (ProbeTimingConfig{}) {
+		return newConfigError("The http healthcheck has no url declared")
+	}
+	if err := validateProbeTiming(healthcheck.Http.ProbeTimingConfig, "http"); err != nil {
+		return err
+	}
+
+	if _, err := regexp.Compile(healthcheck.OutputRegex); err != nil {
+		return newConfigError("The output regex is invalid: %s", err)
+	}
+
+	if len(healthcheck.File.Path) == 0 && healthcheck.File.ProbeTimingConfig != (ProbeTimingConfig{}) {
+		return newConfigError("The file healthcheck has no path declared")
+	}
+	if err := validateProbeTiming(healthcheck.File.ProbeTimingConfig, "file"); err != nil {
+		return err
+	}
+
+	if len(healthcheck.Cmd.Cmd) == 0 && healthcheck.Cmd.ProbeTimingConfig != (ProbeTimingConfig{}) {
+		return newConfigError("The cmd healthcheck has no command declared")
+	}
+	if err := validateProbeTiming(healthcheck.Cmd.ProbeTimingConfig, "cmd"); err != nil {
+		return err
+	}
+
+	if healthcheck.Liveness.Enabled {
+		if healthcheck.Port == 0 && len(healthcheck.Http.Url) == 0 && len(healthcheck.File.Path) == 0 && len(healthcheck.Cmd.Cmd) == 0 {
+			return newConfigError("The liveness probe requires a port, http, file or cmd healthcheck")
+		}
+		if healthcheck.Liveness.Interval < 0 || healthcheck.Liveness.Timeout < 0 {
+			return newConfigError("The interval and the timeout of the liveness probe cannot be negative")
+		}
+		if healthcheck.Liveness.FailureThreshold < 0 {
+			return newConfigError("The failure threshold of the liveness probe cannot be negative")
+		}
+	}
+
+	return nil
+}
+
+func validateProbeTiming(timing ProbeTimingConfig, probeName string) error {
+	if timing.Interval < 0 || timing.Timeout < 0 {
+		return newConfigError("The interval and the timeout of the %s healthcheck cannot be negative", probeName)
+	}
+
+	return nil
+}
+
+func validateRestartPolicy(policy RestartPolicyConfig) error {
+	if policy.InitialDelay < 0 || policy.MaxDelay < 0 || policy.Window < 0 {
+		return newConfigError("The delays of the restart policy cannot be negative")
+	}
+	if policy.Multiplier != 0 && policy.Multiplier < 1 {
+		return newConfigError("The multiplier of the restart policy must be greater than or equal to 1")
+	}
+	if policy.MaxRestarts < 0 {
+		return newConfigError("The maximum number of restarts cannot be negative")
+	}
 
 	return nil
 }
@@ -189,6 +511,10 @@ func validateConfig(cfg *ConfigFile) error {
 		return newConfigError("No job and no service is declared in the configuration")
 	}
 
+	if err := validateEnv(cfg.EnvConfig); err != nil {
+		return newConfigError("The global environment is invalid: %s", err)
+	}
+
 	jobNames := make(map[string]bool)
 	for jobIdx, job := range cfg.Jobs {
 		if len(job.Name) == 0 {
@@ -214,22 +540,79 @@ func validateConfig(cfg *ConfigFile) error {
 		if err := validateTaskConfig(service.TaskConfig); err != nil {
 			return newConfigError("The service \"%s\" is invalid: %s", serviceId, err)
 		}
+
+		if len(service.Needs) > 0 {
+			return newConfigError("The service \"%s\" declares needs, the dependencies between services are declared with dependencies", serviceId)
+		}
+		if service.HasConditions() {
+			return newConfigError("The service \"%s\" declares conditions, which are only supported in the jobs", serviceId)
+		}
+		if len(service.Sources) > 0 {
+			return newConfigError("The service \"%s\" declares sources, which are only supported in the jobs", serviceId)
+		}
+		if service.Timeout > 0 || service.Retries > 0 {
+			return newConfigError("The service \"%s\" declares a timeout or retries, the restarts of a service are configured with its restart policy", serviceId)
+		}
+
+		if err := validateHealthcheck(service.Healthcheck); err != nil {
+			return newConfigError("The service \"%s\" has an invalid healthcheck: %s", serviceId, err)
+		}
+
+		if err := validateRestartPolicy(service.RestartPolicy); err != nil {
+			return newConfigError("The service \"%s\" has an invalid restart policy: %s", serviceId, err)
+		}
 	}
 
 	return nil
 }
 
-// parseConfig reads the content of the file at the absolute path given in argument, and extracts its yaml content into a ConfigFile.
+// ParseConfig extracts the yaml content given in argument into a ConfigFile.
+// The variables are interpolated, and the included files are resolved, from the current working directory.
 func ParseConfig(fileContent []byte) (*ConfigFile, error) {
-	output := ConfigFile{}
-	if err := yaml.Unmarshal(fileContent, &output); err != nil {
+	basePath, err := os.Getwd()
+	if err != nil {
+		return nil, newConfigError("Failed to read the current working directory: %s", err)
+	}
+
+	return parseConfig(fileContent, basePath, "main configuration")
+}
+
+// parseConfig extracts the yaml content into a ConfigFile, merges the included files and validates the result.
+// The source is the name of the main file, used in the error messages.
+func parseConfig(fileContent []byte, basePath, source string) (*ConfigFile, error) {
+	output, err := decodeConfig(fileContent, basePath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := newIncludeResolver(output, basePath, source)
+	if err := resolver.resolve(output.Include, basePath, output.Vars, []string{source}); err != nil {
+		return nil, newConfigError("The configuration is invalid: %s", err)
+	}
+
+	if err := validateConfig(output); err != nil {
+		return nil, newConfigError("The configuration is invalid: %s", err)
+	}
+
+	return output, nil
+}
+
+// decodeConfig extracts the yaml content into a ConfigFile, after the variables have been interpolated
+func decodeConfig(fileContent []byte, basePath string, inheritedVars map[string]string) (*ConfigFile, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(fileContent, &document); err != nil {
 		return nil, newConfigError("The file could not be parsed from YAML: %s", err.Error())
 	}
 
-	if err := validateConfig(&output); err != nil {
+	if err := interpolateDocument(&document, basePath, inheritedVars); err != nil {
 		return nil, newConfigError("The configuration is invalid: %s", err)
 	}
 
+	output := ConfigFile{}
+	if err := document.Decode(&output); err != nil {
+		return nil, newConfigError("The file could not be parsed from YAML: %s", err.Error())
+	}
+
 	return &output, nil
 }
 
@@ -244,7 +627,7 @@ func FindAndParseConfig(givenPath string) (*ConfigFile, error) {
 		return nil, newConfigError("The contents of the file \"%s\" could not be read: %s", configPath, err)
 	}
 
-	config, err := ParseConfig(fileContent)
+	config, err := parseConfig(fileContent, filepath.Dir(configPath), configPath)
 	if err != nil {
 		return nil, err
 	}
@@ -2,6 +2,7 @@ package cfg
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -23,6 +24,8 @@ jobs:
 
           - name: sleep
             cmd: sleep 5
+            stop_signal: SIGINT
+            stop_timeout: 3s
         run_after:
           - name: other command
             cmd: other-command
@@ -39,6 +42,31 @@ services:
     path: .
     healthcheck:
       port: 4242
+      http:
+        url: http://localhost:4242/health
+        method: HEAD
+        expected_status: [200, 204]
+        insecure: true
+        interval: 2s
+      file:
+        path: ./tmp/server.pid
+        interval: 100ms
+      cmd:
+        cmd: pg_isready
+        interval: 1s
+        timeout: 30s
+      liveness:
+        enabled: true
+        interval: 5s
+        timeout: 2s
+    auto_restart: true
+    stop_cmd: docker compose down
+    restart_policy:
+      initial_delay: 500ms
+      multiplier: 1.5
+      max_delay: 10s
+      max_restarts: 3
+      window: 2m
     open_target: "http://localhost:4242/something"
     dependencies:
       - target: Service B
@@ -61,6 +89,8 @@ services:
 	assert.Equal(t, config.Jobs[0].Steps[0].RunBefore[0].Cmd, "my-command")
 	assert.Len(t, config.Jobs[0].Steps[0].Tasks, 2)
 	assert.Equal(t, config.Jobs[0].Steps[0].Tasks[1].Cmd, "sleep 5")
+	assert.Equal(t, "SIGINT", config.Jobs[0].Steps[0].Tasks[1].StopSignal)
+	assert.Equal(t, 3*time.Second, config.Jobs[0].Steps[0].Tasks[1].StopTimeout)
 	assert.Len(t, config.Jobs[0].Steps[0].RunAfter, 1)
 
 	assert.Len(t, config.Jobs[0].Steps[1].RunBefore, 0)
@@ -71,6 +101,26 @@ services:
 	assert.Equal(t, config.Services["serviceA"].Name, "Service A")
 	assert.Equal(t, config.Services["serviceA"].Cmd, "echo 'A'")
 	assert.Equal(t, config.Services["serviceA"].Healthcheck.Port, 4242)
+	assert.Equal(t, "http://localhost:4242/health", config.Services["serviceA"].Healthcheck.Http.Url)
+	assert.Equal(t, "HEAD", config.Services["serviceA"].Healthcheck.Http.Method)
+	assert.Equal(t, []int{200, 204}, config.Services["serviceA"].Healthcheck.Http.ExpectedStatus)
+	assert.True(t, config.Services["serviceA"].Healthcheck.Http.Insecure)
+	assert.Equal(t, 2*time.Second, config.Services["serviceA"].Healthcheck.Http.Interval)
+	assert.Equal(t, "./tmp/server.pid", config.Services["serviceA"].Healthcheck.File.Path)
+	assert.Equal(t, 100*time.Millisecond, config.Services["serviceA"].Healthcheck.File.Interval)
+	assert.Equal(t, time.Duration(0), config.Services["serviceA"].Healthcheck.File.Timeout)
+	assert.Equal(t, "pg_isready", config.Services["serviceA"].Healthcheck.Cmd.Cmd)
+	assert.Equal(t, time.Second, config.Services["serviceA"].Healthcheck.Cmd.Interval)
+	assert.Equal(t, 30*time.Second, config.Services["serviceA"].Healthcheck.Cmd.Timeout)
+	assert.Equal(t, 5*time.Second, config.Services["serviceA"].Healthcheck.Liveness.Interval)
+	assert.Equal(t, 2*time.Second, config.Services["serviceA"].Healthcheck.Liveness.Timeout)
+	assert.True(t, config.Services["serviceA"].AutoRestart)
+	assert.Equal(t, "docker compose down", config.Services["serviceA"].StopCmd)
+	assert.Equal(t, 500*time.Millisecond, config.Services["serviceA"].RestartPolicy.InitialDelay)
+	assert.Equal(t, 1.5, config.Services["serviceA"].RestartPolicy.Multiplier)
+	assert.Equal(t, 10*time.Second, config.Services["serviceA"].RestartPolicy.MaxDelay)
+	assert.Equal(t, 3, config.Services["serviceA"].RestartPolicy.MaxRestarts)
+	assert.Equal(t, 2*time.Minute, config.Services["serviceA"].RestartPolicy.Window)
 	assert.Equal(t, config.Services["serviceA"].OpenTarget, "http://localhost:4242/something")
 	assert.Equal(t, config.Services["serviceB"].Name, "Service B")
 	assert.Len(t, config.Services["serviceA"].Dependencies, 1)
@@ -132,6 +182,94 @@ jobs:
 	_, err = ParseConfig([]byte(noStepName))
 	assert.ErrorContains(t, err, "The step #0 in the job \"My first job\" has no name declared")
 
+	invalidJobHook := `
+jobs:
+  - name: My first job
+    steps:
+        - name: first_step
+          tasks:
+            - name: first_task
+              cmd: echo 1
+    run_after:
+        - path: .
+`
+	_, err = ParseConfig([]byte(invalidJobHook))
+	assert.ErrorContains(t, err, "The job \"My first job\" has invalid run_after hooks: The task #0 has no command declared")
+
+	invalidStopSignal := `
+jobs:
+  - name: My first job
+    steps:
+        - name: first_step
+          tasks:
+            - name: first_task
+              cmd: echo 1
+              stop_signal: SIGFOO
+`
+	_, err = ParseConfig([]byte(invalidStopSignal))
+	assert.ErrorContains(t, err, "The task \"first_task\" in the step \"first_step\" in the job \"My first job\" is invalid: The stop signal \"SIGFOO\" is not supported")
+
+	invalidParamConfig := `
+jobs:
+  - name: My first job
+    params:
+      - name: env
+        type: enum
+        values: [staging, production]
+        default: dev
+    steps:
+        - name: first_step
+          tasks:
+            - name: first_task
+              cmd: echo 1
+`
+	_, err = ParseConfig([]byte(invalidParamConfig))
+	assert.ErrorContains(t, err, "The job \"My first job\" has invalid parameters: The default value of the parameter \"env\" is invalid")
+
+	invalidEnvConfig := `
+jobs:
+  - name: My first job
+    env:
+      INVALID-NAME: value
+    steps:
+        - name: first_step
+          tasks:
+            - name: first_task
+              cmd: echo 1
+`
+	_, err = ParseConfig([]byte(invalidEnvConfig))
+	assert.ErrorContains(t, err, "The job \"My first job\" has an invalid environment: The environment variable name \"INVALID-NAME\" is invalid")
+
+	invalidFailedWhenConfig := `
+jobs:
+  - name: My first job
+    steps:
+        - name: first_step
+          tasks:
+            - name: first_task
+              cmd: echo 1
+              success_exit_codes: [0, 1]
+              failed_when:
+                output_matches: "(unclosed"
+`
+	_, err = ParseConfig([]byte(invalidFailedWhenConfig))
+	assert.ErrorContains(t, err, "The output_matches regex is invalid")
+
+	invalidConditionConfig := `
+jobs:
+  - name: My first job
+    steps:
+        - name: first_step
+          when:
+            params:
+              unknown: value
+          tasks:
+            - name: first_task
+              cmd: echo 1
+`
+	_, err = ParseConfig([]byte(invalidConditionConfig))
+	assert.ErrorContains(t, err, "The step \"first_step\" in the job \"My first job\" is invalid: The when condition is invalid: The job has no parameter named \"unknown\"")
+
 	duplicateStepNameConfig := `
 jobs:
   - name: My first job
@@ -164,3 +302,105 @@ jobs:
 	_, err = ParseConfig([]byte(duplicateTasksNames))
 	assert.ErrorContains(t, err, "There are multiple tasks named \"first_task\" in the step \"first_step\"")
 }
+
+func TestServiceErrors(t *testing.T) {
+	t.Parallel()
+
+	invalidMultiplier := `
+services:
+  serviceA:
+    name: Service A
+    cmd: echo 'A'
+    auto_restart: true
+    restart_policy:
+      multiplier: 0.5
+`
+	_, err := ParseConfig([]byte(invalidMultiplier))
+	assert.ErrorContains(t, err, "The service \"serviceA\" has an invalid restart policy: The multiplier of the restart policy must be greater than or equal to 1")
+
+	invalidHealthcheckUrl := `
+services:
+  serviceA:
+    name: Service A
+    cmd: echo 'A'
+    healthcheck:
+      http:
+        url: ftp://localhost/health
+`
+	_, err = ParseConfig([]byte(invalidHealthcheckUrl))
+	assert.ErrorContains(t, err, "The service \"serviceA\" has an invalid healthcheck: The url \"ftp://localhost/health\" must use the http or https scheme")
+
+	invalidBodyRegex := `
+services:
+  serviceA:
+    name: Service A
+    cmd: echo 'A'
+    healthcheck:
+      http:
+        url: http://localhost:8080/health
+        expected_status: [200, 204]
+        body_regex: "(unclosed"
+`
+	_, err = ParseConfig([]byte(invalidBodyRegex))
+	assert.ErrorContains(t, err, "The body regex is invalid")
+
+	invalidOutputRegex := `
+services:
+  serviceA:
+    name: Service A
+    cmd: echo 'A'
+    healthcheck:
+      output_regex: "Listening on ["
+`
+	_, err = ParseConfig([]byte(invalidOutputRegex))
+	assert.ErrorContains(t, err, "The output regex is invalid")
+
+	livenessWithoutProbe := `
+services:
+  serviceA:
+    name: Service A
+    cmd: echo 'A'
+    healthcheck:
+      output_regex: "Listening"
+      liveness:
+        enabled: true
+`
+	_, err = ParseConfig([]byte(livenessWithoutProbe))
+	assert.ErrorContains(t, err, "The liveness probe requires a port, http, file or cmd healthcheck")
+
+	negativeProbeTimeout := `
+services:
+  serviceA:
+    name: Service A
+    cmd: echo 'A'
+    healthcheck:
+      cmd:
+        cmd: pg_isready
+        timeout: -1s
+`
+	_, err = ParseConfig([]byte(negativeProbeTimeout))
+	assert.ErrorContains(t, err, "The interval and the timeout of the cmd healthcheck cannot be negative")
+
+	timingWithoutProbe := `
+services:
+  serviceA:
+    name: Service A
+    cmd: echo 'A'
+    healthcheck:
+      file:
+        interval: 1s
+`
+	_, err = ParseConfig([]byte(timingWithoutProbe))
+	assert.ErrorContains(t, err, "The file healthcheck has no path declared")
+
+	negativeDelay := `
+services:
+  serviceA:
+    name: Service A
+    cmd: echo 'A'
+    restart_policy:
+      initial_delay: -1s
+`
+	_, err = ParseConfig([]byte(negativeDelay))
+	assert.ErrorContains(t, err, "The delays of the restart policy cannot be negative")
+}
@@ -0,0 +1,169 @@
+package cfg
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Environment variables passed to the commands, either declared directly or loaded from dotenv files
+type EnvConfig struct {
+	Env     map[string]string `yaml:"env,omitempty"`
+	EnvFile []string          `yaml:"env_file,omitempty"`
+}
+
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func validateEnv(env EnvConfig) error {
+	for name := range env.Env {
+		if !envNameRegex.MatchString(name) {
+			return newConfigError("The environment variable name \"%s\" is invalid", name)
+		}
+	}
+	for _, path := range env.EnvFile {
+		if len(path) == 0 {
+			return newConfigError("An env_file has an empty path")
+		}
+	}
+
+	return nil
+}
+
+// ResolveEnv loads the environment variables of the given levels, and returns them as a list of "NAME=value".
+// The levels are given from the lowest to the highest precedence, and within a level, the variables
+// declared in env override the ones loaded from the env_file list (which override each other in order).
+// The relative paths of the env files are resolved from the base path.
+func ResolveEnv(basePath string, levels ...EnvConfig) ([]string, error) {
+	resolved := make(map[string]string)
+	for _, level := range levels {
+		for _, path := range level.EnvFile {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(basePath, path)
+			}
+
+			fileVars, err := LoadEnvFile(path)
+			if err != nil {
+				return nil, err
+			}
+			maps.Copy(resolved, fileVars)
+		}
+		maps.Copy(resolved, level.Env)
+	}
+
+	env := make([]string, 0, len(resolved))
+	for _, name := range slices.Sorted(maps.Keys(resolved)) {
+		env = append(env, fmt.Sprintf("%s=%s", name, resolved[name]))
+	}
+
+	return env, nil
+}
+
+// LoadEnvFile reads and parses the dotenv file at the given path
+func LoadEnvFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, newConfigError("The env file \"%s\" could not be read: %s", path, err)
+	}
+
+	vars, err := ParseDotenv(string(content))
+	if err != nil {
+		return nil, newConfigError("The env file \"%s\" is invalid: %s", path, err)
+	}
+
+	return vars, nil
+}
+
+// ParseDotenv parses the content of a dotenv file. It supports comments, "export" prefixes,
+// unquoted values, single-quoted literal values, and double-quoted values with escape sequences,
+// which can span several lines.
+func ParseDotenv(content string) (map[string]string, error) {
+	vars := make(map[string]string)
+	lineNumber := 0
+
+	for len(content) > 0 {
+		var line string
+		line, content, _ = strings.Cut(content, "\n")
+		lineNumber++
+
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		name, value, found := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !found || !envNameRegex.MatchString(name) {
+			return nil, newConfigError("The line %d is not a valid variable declaration", lineNumber)
+		}
+		value = strings.TrimLeft(value, " \t")
+
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, newConfigError("The value on line %d has no closing quote", lineNumber)
+			}
+			vars[name] = value[1 : end+1]
+
+		case strings.HasPrefix(value, `"`):
+			// Double-quoted values can continue on the next lines
+			startLine := lineNumber
+			parsed, ok := parseDoubleQuoted(value[1:])
+			for !ok && len(content) > 0 {
+				var nextLine string
+				nextLine, content, _ = strings.Cut(content, "\n")
+				lineNumber++
+				value += "\n" + nextLine
+				parsed, ok = parseDoubleQuoted(value[1:])
+			}
+			if !ok {
+				return nil, newConfigError("The value on line %d has no closing quote", startLine)
+			}
+			vars[name] = parsed
+
+		default:
+			// Unquoted values end at the first comment
+			if commentIdx := strings.Index(value, " #"); commentIdx >= 0 {
+				value = value[:commentIdx]
+			}
+			vars[name] = strings.TrimSpace(value)
+		}
+	}
+
+	return vars, nil
+}
+
+// Reads a double-quoted value up to its closing quote, and returns false if the quote is not closed
+func parseDoubleQuoted(value string) (string, bool) {
+	var parsed strings.Builder
+	for idx := 0; idx < len(value); idx++ {
+		switch value[idx] {
+		case '"':
+			return parsed.String(), true
+		case '\\':
+			if idx+1 >= len(value) {
+				return "", false
+			}
+			idx++
+			switch value[idx] {
+			case 'n':
+				parsed.WriteByte('\n')
+			case 't':
+				parsed.WriteByte('\t')
+			case 'r':
+				parsed.WriteByte('\r')
+			default:
+				parsed.WriteByte(value[idx])
+			}
+		default:
+			parsed.WriteByte(value[idx])
+		}
+	}
+
+	return "", false
+}
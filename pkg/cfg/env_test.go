@@ -0,0 +1,60 @@
+package cfg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDotenv(t *testing.T) {
+	t.Parallel()
+
+	content := `
+# A comment
+PLAIN=value
+export EXPORTED=exported value
+SPACED = spaced   # with a comment
+SINGLE='single # quoted\n'
+DOUBLE="double \"quoted\"\n"
+MULTILINE="first line
+second line"
+EMPTY=
+`
+	vars, err := ParseDotenv(content)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"PLAIN":     "value",
+		"EXPORTED":  "exported value",
+		"SPACED":    "spaced",
+		"SINGLE":    `single # quoted\n`,
+		"DOUBLE":    "double \"quoted\"\n",
+		"MULTILINE": "first line\nsecond line",
+		"EMPTY":     "",
+	}, vars)
+
+	_, err = ParseDotenv("VALID=1\nnot a declaration\n")
+	assert.ErrorContains(t, err, "The line 2 is not a valid variable declaration")
+
+	_, err = ParseDotenv("UNCLOSED=\"value\nOTHER=1\n")
+	assert.ErrorContains(t, err, "The value on line 1 has no closing quote")
+}
+
+func TestResolveEnv(t *testing.T) {
+	t.Parallel()
+
+	basePath := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(basePath, ".env"), []byte("A=from file\nB=from file\nC=from file\n"), 0o644))
+
+	env, err := ResolveEnv(
+		basePath,
+		EnvConfig{EnvFile: []string{".env"}, Env: map[string]string{"B": "from global"}},
+		EnvConfig{Env: map[string]string{"C": "from command"}},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"A=from file", "B=from global", "C=from command"}, env)
+
+	_, err = ResolveEnv(basePath, EnvConfig{EnvFile: []string{"missing.env"}})
+	assert.ErrorContains(t, err, "missing.env\" could not be read")
+}
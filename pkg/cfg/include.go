@@ -0,0 +1,231 @@
+package cfg
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Merges the jobs and services of the included files into the main configuration,
+// and keeps track of the file that declared each of them to report the duplicates.
+type includeResolver struct {
+	config         *ConfigFile
+	rootBasePath   string
+	jobOrigins     map[string]string
+	serviceOrigins map[string]string
+
+	// Files already merged, that are skipped when they are reached again through another pattern or include
+	includedPaths map[string]bool
+}
+
+func newIncludeResolver(config *ConfigFile, rootBasePath, rootSource string) *includeResolver {
+	resolver := &includeResolver{
+		config:         config,
+		rootBasePath:   rootBasePath,
+		jobOrigins:     make(map[string]string),
+		serviceOrigins: make(map[string]string),
+		includedPaths:  make(map[string]bool),
+	}
+
+	// The duplicates within the main file are reported by the validation
+	for _, job := range config.Jobs {
+		resolver.jobOrigins[job.Name] = rootSource
+	}
+	for serviceId := range config.Services {
+		resolver.serviceOrigins[serviceId] = rootSource
+	}
+
+	return resolver
+}
+
+// Loads the files included by a configuration file, then the files they include themselves.
+// The includeStack lists the files being included, to detect the circular includes.
+func (r *includeResolver) resolve(includes []string, includingDir string, vars map[string]string, includeStack []string) error {
+	for _, pattern := range includes {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(includingDir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return newConfigError("The include pattern \"%s\" is invalid: %s", pattern, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+			return newConfigError("The included file \"%s\" does not exist", pattern)
+		}
+
+		for _, includedPath := range matches {
+			if slices.Contains(includeStack, includedPath) {
+				return newConfigError("The file \"%s\" is included recursively", includedPath)
+			}
+			if r.includedPaths[includedPath] {
+				continue
+			}
+			r.includedPaths[includedPath] = true
+
+			if err := r.include(includedPath, vars, includeStack); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *includeResolver) include(includedPath string, vars map[string]string, includeStack []string) error {
+	fileContent, err := os.ReadFile(includedPath)
+	if err != nil {
+		return newConfigError("The contents of the included file \"%s\" could not be read: %s", includedPath, err)
+	}
+
+	// The included file can use the variables of the files including it
+	included, err := decodeConfig(fileContent, r.rootBasePath, vars)
+	if err != nil {
+		return newConfigError("The included file \"%s\" is invalid: %s", includedPath, err)
+	}
+	if len(included.LogFilePath) > 0 {
+		return newConfigError("The included file \"%s\" cannot declare a log_file", includedPath)
+	}
+
+	// The relative paths of an included file are relative to its own directory
+	includedDir := filepath.Dir(includedPath)
+	fileEnv := relocateEnv(included.EnvConfig, includedDir)
+
+	for _, job := range included.Jobs {
+		if origin, exists := r.jobOrigins[job.Name]; exists {
+			return newConfigError("The job \"%s\" is declared both in \"%s\" and in \"%s\"", job.Name, origin, includedPath)
+		}
+		r.jobOrigins[job.Name] = includedPath
+
+		relocateJob(&job, includedDir)
+		job.FileEnv = fileEnv
+		r.config.Jobs = append(r.config.Jobs, job)
+	}
+
+	for _, serviceId := range slices.Sorted(maps.Keys(included.Services)) {
+		if origin, exists := r.serviceOrigins[serviceId]; exists {
+			return newConfigError("The service \"%s\" is declared both in \"%s\" and in \"%s\"", serviceId, origin, includedPath)
+		}
+		r.serviceOrigins[serviceId] = includedPath
+
+		service := included.Services[serviceId]
+		relocateService(&service, includedDir)
+		service.FileEnv = fileEnv
+		if r.config.Services == nil {
+			r.config.Services = make(map[string]ServiceConfig)
+		}
+		r.config.Services[serviceId] = service
+	}
+
+	includedVars := make(map[string]string)
+	maps.Copy(includedVars, vars)
+	maps.Copy(includedVars, included.Vars)
+
+	return r.resolve(included.Include, includedDir, includedVars, append(slices.Clone(includeStack), includedPath))
+}
+
+// Returns the path relative to the directory, or the path itself if it is absolute
+func relocatePath(path, dir string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(dir, path)
+}
+
+func relocatePaths(paths []string, dir string) []string {
+	if paths == nil {
+		return nil
+	}
+
+	relocated := make([]string, len(paths))
+	for idx, path := range paths {
+		relocated[idx] = relocatePath(path, dir)
+	}
+
+	return relocated
+}
+
+func relocateEnv(env EnvConfig, dir string) EnvConfig {
+	relocated := EnvConfig{Env: env.Env}
+	for _, path := range env.EnvFile {
+		relocated.EnvFile = append(relocated.EnvFile, relocatePath(path, dir))
+	}
+
+	return relocated
+}
+
+func relocateCommands(commands []CmdConfig, dir string) []CmdConfig {
+	relocated := make([]CmdConfig, len(commands))
+	for idx, cmd := range commands {
+		cmd.Path = relocatePath(cmd.Path, dir)
+		cmd.EnvConfig = relocateEnv(cmd.EnvConfig, dir)
+		relocated[idx] = cmd
+	}
+
+	return relocated
+}
+
+func relocateCondition(condition *ConditionConfig, dir string) *ConditionConfig {
+	if condition == nil {
+		return nil
+	}
+
+	relocated := *condition
+	if len(relocated.File) > 0 {
+		relocated.File = relocatePath(relocated.File, dir)
+	}
+	relocated.Path = relocatePath(relocated.Path, dir)
+
+	return &relocated
+}
+
+func relocateConditions(conditions ConditionsConfig, dir string) ConditionsConfig {
+	return ConditionsConfig{
+		When:   relocateCondition(conditions.When, dir),
+		SkipIf: relocateCondition(conditions.SkipIf, dir),
+	}
+}
+
+func relocateTask(task *TaskConfig, dir string) {
+	task.Path = relocatePath(task.Path, dir)
+	task.ConditionsConfig = relocateConditions(task.ConditionsConfig, dir)
+	task.Sources = relocatePaths(task.Sources, dir)
+	task.Generates = relocatePaths(task.Generates, dir)
+	task.CmdConfig.EnvConfig = relocateEnv(task.CmdConfig.EnvConfig, dir)
+	task.RunBefore = relocateCommands(task.RunBefore, dir)
+	task.RunAfter = relocateCommands(task.RunAfter, dir)
+}
+
+func relocateJob(job *JobConfig, dir string) {
+	job.EnvConfig = relocateEnv(job.EnvConfig, dir)
+	job.RunAfter = relocateCommands(job.RunAfter, dir)
+	job.Watch = relocatePaths(job.Watch, dir)
+
+	steps := make([]StepConfig, len(job.Steps))
+	for stepIdx, step := range job.Steps {
+		step.EnvConfig = relocateEnv(step.EnvConfig, dir)
+		step.ConditionsConfig = relocateConditions(step.ConditionsConfig, dir)
+		step.Watch = relocatePaths(step.Watch, dir)
+		step.RunBefore = relocateCommands(step.RunBefore, dir)
+		step.RunAfter = relocateCommands(step.RunAfter, dir)
+
+		tasks := make([]TaskConfig, len(step.Tasks))
+		for taskIdx, task := range step.Tasks {
+			relocateTask(&task, dir)
+			tasks[taskIdx] = task
+		}
+		step.Tasks = tasks
+		steps[stepIdx] = step
+	}
+	job.Steps = steps
+}
+
+func relocateService(service *ServiceConfig, dir string) {
+	relocateTask(&service.TaskConfig, dir)
+	if len(service.Healthcheck.File.Path) > 0 {
+		service.Healthcheck.File.Path = relocatePath(service.Healthcheck.File.Path, dir)
+	}
+}
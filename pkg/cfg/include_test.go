@@ -0,0 +1,110 @@
+package cfg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestInclude(t *testing.T) {
+	t.Parallel()
+
+	basePath := t.TempDir()
+	rootPath := filepath.Join(basePath, "tera.yml")
+	rootConfig := `
+vars:
+  REGISTRY: registry.local
+include:
+  - teams/*/tera.yml
+jobs:
+  - name: root job
+    steps:
+      - name: step
+        tasks:
+          - name: task
+            cmd: echo root
+`
+	writeConfigFile(t, filepath.Join(basePath, "teams/front/tera.yml"), `
+env_file:
+  - front.env
+jobs:
+  - name: front build
+    steps:
+      - name: step
+        tasks:
+          - name: build
+            cmd: docker build -t ${REGISTRY}/front .
+          - name: lint
+            cmd: npm run lint
+            path: /absolute/path
+`)
+	writeConfigFile(t, filepath.Join(basePath, "teams/back/tera.yml"), `
+services:
+  api:
+    name: API
+    cmd: ./serve
+    path: api
+    healthcheck:
+      file:
+        path: api/ready
+`)
+
+	config, err := parseConfig([]byte(rootConfig), basePath, rootPath)
+	assert.Nil(t, err)
+	assert.Len(t, config.Jobs, 2)
+
+	frontJob := config.Jobs[1]
+	assert.Equal(t, "front build", frontJob.Name)
+	assert.Equal(t, "docker build -t registry.local/front .", frontJob.Steps[0].Tasks[0].Cmd)
+	assert.Equal(t, filepath.Join(basePath, "teams/front"), frontJob.Steps[0].Tasks[0].Path)
+	assert.Equal(t, "/absolute/path", frontJob.Steps[0].Tasks[1].Path)
+	assert.Equal(t, []string{filepath.Join(basePath, "teams/front/front.env")}, frontJob.FileEnv.EnvFile)
+
+	api := config.Services["api"]
+	assert.Equal(t, filepath.Join(basePath, "teams/back/api"), api.Path)
+	assert.Equal(t, filepath.Join(basePath, "teams/back/api/ready"), api.Healthcheck.File.Path)
+
+	// A duplicate name is reported with the files declaring it
+	writeConfigFile(t, filepath.Join(basePath, "teams/other/tera.yml"), `
+jobs:
+  - name: root job
+    steps: []
+`)
+	_, err = parseConfig([]byte(rootConfig), basePath, rootPath)
+	assert.ErrorContains(t, err, "The job \"root job\" is declared both in \""+rootPath+"\" and in \""+filepath.Join(basePath, "teams/other/tera.yml")+"\"")
+
+	// Missing files and circular includes are errors
+	_, err = parseConfig([]byte("include: [missing.yml]"), basePath, rootPath)
+	assert.ErrorContains(t, err, "The included file \""+filepath.Join(basePath, "missing.yml")+"\" does not exist")
+
+	writeConfigFile(t, filepath.Join(basePath, "loop/a.yml"), "include: [b.yml]")
+	writeConfigFile(t, filepath.Join(basePath, "loop/b.yml"), "include: [a.yml]")
+	_, err = parseConfig([]byte("include: [loop/a.yml]"), basePath, rootPath)
+	assert.ErrorContains(t, err, "is included recursively")
+
+	// A file reached several times, through two patterns or a diamond include, is only merged once
+	writeConfigFile(t, filepath.Join(basePath, "diamond/shared.yml"), `
+jobs:
+  - name: shared job
+    steps:
+      - name: step
+        tasks:
+          - name: task
+            cmd: echo shared
+`)
+	writeConfigFile(t, filepath.Join(basePath, "diamond/left.yml"), "include: [shared.yml]")
+	writeConfigFile(t, filepath.Join(basePath, "diamond/right.yml"), "include: [shared.yml]")
+	config, err = parseConfig([]byte("include: [diamond/left.yml, diamond/right.yml, diamond/*.yml]"), basePath, rootPath)
+	assert.Nil(t, err)
+	assert.Len(t, config.Jobs, 1)
+	assert.Equal(t, "shared job", config.Jobs[0].Name)
+}
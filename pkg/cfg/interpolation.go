@@ -0,0 +1,161 @@
+package cfg
+
+import (
+	"maps"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Matches "${NAME}" and "${NAME:-default}", along with the escaped form "$${"
+var interpolationRegex = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// Variables that can be referenced in the configuration: the ones of the vars section, and the built-in variables.
+// The variables of the shell that runs the commands must be escaped as "$${NAME}".
+type interpolationScope struct {
+	vars     map[string]string
+	builtins map[string]string
+}
+
+func (s interpolationScope) lookup(name string) (string, bool) {
+	if value, ok := s.vars[name]; ok {
+		return value, true
+	}
+	value, ok := s.builtins[name]
+
+	return value, ok
+}
+
+// Returns a copy of the scope with an additional built-in variable
+func (s interpolationScope) withBuiltin(name, value string) interpolationScope {
+	builtins := maps.Clone(s.builtins)
+	builtins[name] = value
+
+	return interpolationScope{vars: s.vars, builtins: builtins}
+}
+
+// Replaces the variables referenced in the string. A reference to an undefined variable
+// without a default value is an error.
+func (s interpolationScope) interpolate(value string, line int) (string, error) {
+	var err error
+	result := interpolationRegex.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+
+		groups := interpolationRegex.FindStringSubmatch(match)
+		resolved, ok := s.lookup(groups[1])
+		if strings.Contains(match, ":-") && (!ok || len(resolved) == 0) {
+			return groups[2]
+		}
+		if ok {
+			return resolved
+		}
+
+		if err == nil {
+			err = newConfigError("The variable \"%s\" used on line %d is not defined", groups[1], line)
+		}
+		return match
+	})
+
+	return result, err
+}
+
+// Resolves the variables in all the values of the yaml document, before it is decoded.
+// The built-in variables are TERA_BASE_PATH, and SERVICE_ID in the definition of a service.
+// The inherited variables are the ones of the files including this document.
+func interpolateDocument(document *yaml.Node, basePath string, inheritedVars map[string]string) error {
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	root := document.Content[0]
+
+	scope := interpolationScope{
+		vars:     make(map[string]string),
+		builtins: map[string]string{"TERA_BASE_PATH": basePath},
+	}
+	maps.Copy(scope.vars, inheritedVars)
+
+	// The vars are resolved first, in order, so that a variable can reference the ones declared before it
+	for idx := 0; idx+1 < len(root.Content); idx += 2 {
+		if root.Content[idx].Value != "vars" {
+			continue
+		}
+
+		varsNode := root.Content[idx+1]
+		if varsNode.Kind != yaml.MappingNode {
+			return newConfigError("The vars section on line %d must be a mapping", varsNode.Line)
+		}
+		for varIdx := 0; varIdx+1 < len(varsNode.Content); varIdx += 2 {
+			nameNode, valueNode := varsNode.Content[varIdx], varsNode.Content[varIdx+1]
+			if valueNode.Kind != yaml.ScalarNode {
+				return newConfigError("The variable \"%s\" on line %d must be a scalar value", nameNode.Value, valueNode.Line)
+			}
+			if err := interpolateNode(valueNode, scope); err != nil {
+				return err
+			}
+			scope.vars[nameNode.Value] = valueNode.Value
+		}
+	}
+
+	for idx := 0; idx+1 < len(root.Content); idx += 2 {
+		key, value := root.Content[idx].Value, root.Content[idx+1]
+		switch {
+		case key == "vars":
+			continue
+
+		case key == "services" && value.Kind == yaml.MappingNode:
+			for serviceIdx := 0; serviceIdx+1 < len(value.Content); serviceIdx += 2 {
+				serviceScope := scope.withBuiltin("SERVICE_ID", value.Content[serviceIdx].Value)
+				if err := interpolateNode(value.Content[serviceIdx+1], serviceScope); err != nil {
+					return err
+				}
+			}
+
+		default:
+			if err := interpolateNode(value, scope); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Resolves the variables in the values of the node and its children. The keys of the mappings are left untouched.
+func interpolateNode(node *yaml.Node, scope interpolationScope) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "${") {
+			return nil
+		}
+
+		value, err := scope.interpolate(node.Value, node.Line)
+		if err != nil {
+			return err
+		}
+		node.Value = value
+
+		// The type of an unquoted value is resolved again from its new content, so that "port: ${PORT}" is an int
+		if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			node.Tag = ""
+		}
+
+	case yaml.MappingNode:
+		for idx := 1; idx < len(node.Content); idx += 2 {
+			if err := interpolateNode(node.Content[idx], scope); err != nil {
+				return err
+			}
+		}
+
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, child := range node.Content {
+			if err := interpolateNode(child, scope); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
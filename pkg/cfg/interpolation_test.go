@@ -0,0 +1,104 @@
+package cfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolation(t *testing.T) {
+	t.Parallel()
+
+	configText := `
+vars:
+  HOST: localhost
+  API_PORT: 8080
+  API_URL: http://${HOST}:${API_PORT}
+  CODE_DIR: ""
+services:
+  api:
+    name: API
+    cmd: ./serve --port ${API_PORT} --name ${SERVICE_ID} --root ${TERA_BASE_PATH}
+    path: ${CODE_DIR:-src}
+    open_target: ${API_URL}/home
+    healthcheck:
+      port: ${API_PORT}
+jobs:
+  - name: test
+    steps:
+      - name: step
+        tasks:
+          - name: task
+            cmd: echo "$${NOT_INTERPOLATED} ${API_PORT}"
+`
+	config, err := parseConfig([]byte(configText), "/some/project", "tera.yml")
+	assert.Nil(t, err)
+
+	api := config.Services["api"]
+	assert.Equal(t, "./serve --port 8080 --name api --root /some/project", api.Cmd)
+	assert.Equal(t, "src", api.Path)
+	assert.Equal(t, "http://localhost:8080/home", api.OpenTarget)
+	assert.Equal(t, 8080, api.Healthcheck.Port)
+	assert.Equal(t, "echo \"${NOT_INTERPOLATED} 8080\"", config.Jobs[0].Steps[0].Tasks[0].Cmd)
+
+	// The variables of the shell are escaped, and the default value applies to the undefined variables
+	shellVarsConfig := `
+vars:
+  GREETING: hello
+jobs:
+  - name: test
+    params:
+      - name: mode
+        default: debug
+    env:
+      TASK_ONLY: ${GREETING}
+    steps:
+      - name: step
+        tasks:
+          - name: loop
+            cmd: for f in a b; do echo $${f}; done
+          - name: env
+            cmd: echo "$${TASK_ONLY} $${TERA_PARAM_MODE}"
+          - name: default
+            cmd: echo ${UNDECLARED:-fallback} ${GREETING:-fallback}
+`
+	config, err = parseConfig([]byte(shellVarsConfig), "/some/project", "tera.yml")
+	assert.Nil(t, err)
+
+	job := config.Jobs[0]
+	assert.Equal(t, "hello", job.Env["TASK_ONLY"])
+	assert.Equal(t, "for f in a b; do echo ${f}; done", job.Steps[0].Tasks[0].Cmd)
+	assert.Equal(t, "echo \"${TASK_ONLY} ${TERA_PARAM_MODE}\"", job.Steps[0].Tasks[1].Cmd)
+	assert.Equal(t, "echo fallback hello", job.Steps[0].Tasks[2].Cmd)
+}
+
+func TestUndefinedVariable(t *testing.T) {
+	t.Parallel()
+
+	configText := `
+jobs:
+  - name: test
+    steps:
+      - name: step
+        tasks:
+          - name: task
+            cmd: echo ${UNDECLARED}
+`
+	_, err := parseConfig([]byte(configText), "/some/project", "tera.yml")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "The variable \"UNDECLARED\" used on line 8 is not defined")
+
+	// SERVICE_ID is only defined in the services
+	configText = `
+jobs:
+  - name: test
+    steps:
+      - name: step
+        tasks:
+          - name: task
+            cmd: echo ${SERVICE_ID}
+`
+	_, err = parseConfig([]byte(configText), "/some/project", "tera.yml")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "The variable \"SERVICE_ID\" used on line 8 is not defined")
+}
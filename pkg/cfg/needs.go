@@ -0,0 +1,113 @@
+package cfg
+
+// Returns, for each step of the job, the indexes of the steps it needs.
+// If no step declares needs, the steps run one after the other: each step needs the previous one.
+func (job JobConfig) StepNeeds() [][]int {
+	names := make([]string, len(job.Steps))
+	declaredNeeds := make([][]string, len(job.Steps))
+	hasNeeds := false
+	for stepIdx, step := range job.Steps {
+		names[stepIdx] = step.Name
+		declaredNeeds[stepIdx] = step.Needs
+		hasNeeds = hasNeeds || len(step.Needs) > 0
+	}
+
+	if !hasNeeds {
+		needs := make([][]int, len(job.Steps))
+		for stepIdx := 1; stepIdx < len(job.Steps); stepIdx++ {
+			needs[stepIdx] = []int{stepIdx - 1}
+		}
+		return needs
+	}
+
+	return resolveNeeds(names, declaredNeeds)
+}
+
+// Returns, for each task of the step, the indexes of the tasks it needs. The tasks without needs all start at once.
+func (step StepConfig) TaskNeeds() [][]int {
+	names := make([]string, len(step.Tasks))
+	declaredNeeds := make([][]string, len(step.Tasks))
+	for taskIdx, task := range step.Tasks {
+		names[taskIdx] = task.Name
+		declaredNeeds[taskIdx] = task.Needs
+	}
+
+	return resolveNeeds(names, declaredNeeds)
+}
+
+// Converts the names of the needs into indexes. The unknown names are ignored, since they are reported by the validation.
+func resolveNeeds(names []string, declaredNeeds [][]string) [][]int {
+	indexes := make(map[string]int, len(names))
+	for idx, name := range names {
+		indexes[name] = idx
+	}
+
+	needs := make([][]int, len(names))
+	for idx, nodeNeeds := range declaredNeeds {
+		for _, need := range nodeNeeds {
+			if needIdx, ok := indexes[need]; ok {
+				needs[idx] = append(needs[idx], needIdx)
+			}
+		}
+	}
+
+	return needs
+}
+
+// Checks that the needs reference existing names, and that they do not form a cycle
+func validateNeeds(names []string, declaredNeeds [][]string) error {
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		known[name] = true
+	}
+
+	for idx, nodeNeeds := range declaredNeeds {
+		for _, need := range nodeNeeds {
+			if need == names[idx] {
+				return newConfigError("\"%s\" cannot need itself", need)
+			}
+			if !known[need] {
+				return newConfigError("\"%s\" needs \"%s\", which does not exist", names[idx], need)
+			}
+		}
+	}
+
+	if !isNeedsGraphValid(resolveNeeds(names, declaredNeeds)) {
+		return newConfigError("A circular dependency has been detected in the needs")
+	}
+
+	return nil
+}
+
+// Use Kahn's algorithm to ensure the graph of the needs has no cycle
+func isNeedsGraphValid(needs [][]int) bool {
+	indegrees := make([]int, len(needs))
+	for _, nodeNeeds := range needs {
+		for _, need := range nodeNeeds {
+			indegrees[need]++
+		}
+	}
+
+	queue := make([]int, 0)
+	for idx, indegree := range indegrees {
+		if indegree == 0 {
+			queue = append(queue, idx)
+		}
+	}
+
+	visited := 0
+	for len(queue) > 0 {
+		idx := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		visited++
+
+		for _, need := range needs[idx] {
+			indegrees[need]--
+			if indegrees[need] == 0 {
+				queue = append(queue, need)
+			}
+		}
+	}
+
+	return visited == len(needs)
+}
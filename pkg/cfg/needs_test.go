@@ -0,0 +1,89 @@
+package cfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNeeds(t *testing.T) {
+	t.Parallel()
+
+	sequentialConfig := `
+jobs:
+  - name: sequential
+    steps:
+      - name: first
+        tasks:
+          - name: task
+            cmd: echo 1
+      - name: second
+        tasks:
+          - name: task
+            cmd: echo 2
+      - name: third
+        tasks:
+          - name: a
+            cmd: echo a
+          - name: b
+            cmd: echo b
+            needs: [a]
+`
+	config, err := ParseConfig([]byte(sequentialConfig))
+	assert.Nil(t, err)
+	assert.Equal(t, [][]int{nil, {0}, {1}}, config.Jobs[0].StepNeeds())
+	assert.Equal(t, [][]int{nil, {0}}, config.Jobs[0].Steps[2].TaskNeeds())
+
+	dagConfig := `
+jobs:
+  - name: pipeline
+    steps:
+      - name: build
+        tasks:
+          - name: task
+            cmd: echo build
+      - name: lint
+        tasks:
+          - name: task
+            cmd: echo lint
+      - name: integration
+        needs: [build, lint]
+        tasks:
+          - name: task
+            cmd: echo integration
+`
+	config, err = ParseConfig([]byte(dagConfig))
+	assert.Nil(t, err)
+	assert.Equal(t, [][]int{nil, nil, {0, 1}}, config.Jobs[0].StepNeeds())
+
+	cycleConfig := `
+jobs:
+  - name: pipeline
+    steps:
+      - name: first
+        needs: [second]
+        tasks:
+          - name: task
+            cmd: echo 1
+      - name: second
+        needs: [first]
+        tasks:
+          - name: task
+            cmd: echo 2
+`
+	_, err = ParseConfig([]byte(cycleConfig))
+	assert.ErrorContains(t, err, "The steps of the job \"pipeline\" have invalid needs: A circular dependency has been detected in the needs")
+
+	unknownTaskConfig := `
+jobs:
+  - name: pipeline
+    steps:
+      - name: first
+        tasks:
+          - name: task
+            cmd: echo 1
+            needs: [missing]
+`
+	_, err = ParseConfig([]byte(unknownTaskConfig))
+	assert.ErrorContains(t, err, "The tasks of the step \"first\" in the job \"pipeline\" have invalid needs: \"task\" needs \"missing\", which does not exist")
+}
@@ -0,0 +1,112 @@
+package cfg
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	PARAM_TYPE_STRING = "string"
+	PARAM_TYPE_BOOL   = "bool"
+	PARAM_TYPE_ENUM   = "enum"
+)
+
+// Parameter of a job, given on the command line or prompted for when it is missing.
+// A parameter without a default value is required.
+type ParamConfig struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Default     *string  `yaml:"default,omitempty"`
+	Values      []string `yaml:"values,omitempty"`
+}
+
+var paramNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// Returns the type of the parameter, which is a string if it is not declared
+func (p ParamConfig) ParamType() string {
+	if len(p.Type) == 0 {
+		return PARAM_TYPE_STRING
+	}
+
+	return p.Type
+}
+
+// Returns the name of the environment variable that exposes the parameter to the commands
+func (p ParamConfig) EnvName() string {
+	return "TERA_PARAM_" + strings.ToUpper(p.Name)
+}
+
+// Returns the values that the parameter accepts, or nil if it accepts any value
+func (p ParamConfig) Choices() []string {
+	switch p.ParamType() {
+	case PARAM_TYPE_BOOL:
+		return []string{"true", "false"}
+	case PARAM_TYPE_ENUM:
+		return p.Values
+	}
+
+	return nil
+}
+
+// Checks the value given for the parameter, and returns it in its canonical form
+func (p ParamConfig) NormalizeValue(value string) (string, error) {
+	switch p.ParamType() {
+	case PARAM_TYPE_BOOL:
+		switch strings.ToLower(value) {
+		case "yes", "on":
+			return "true", nil
+		case "no", "off":
+			return "false", nil
+		}
+
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", newConfigError("The value \"%s\" of the parameter \"%s\" is not a boolean", value, p.Name)
+		}
+		return strconv.FormatBool(parsed), nil
+
+	case PARAM_TYPE_ENUM:
+		if !slices.Contains(p.Values, value) {
+			return "", newConfigError("The value \"%s\" of the parameter \"%s\" must be one of: %s", value, p.Name, strings.Join(p.Values, ", "))
+		}
+	}
+
+	return value, nil
+}
+
+func validateParams(params []ParamConfig) error {
+	paramNames := make(map[string]bool)
+	for paramIdx, param := range params {
+		if !paramNameRegex.MatchString(param.Name) {
+			return newConfigError("The parameter #%d has an invalid name \"%s\"", paramIdx, param.Name)
+		}
+		if _, exists := paramNames[strings.ToUpper(param.Name)]; exists {
+			return newConfigError("There are multiple parameters named \"%s\"", param.Name)
+		}
+		paramNames[strings.ToUpper(param.Name)] = true
+
+		switch param.ParamType() {
+		case PARAM_TYPE_STRING, PARAM_TYPE_BOOL:
+			if len(param.Values) > 0 {
+				return newConfigError("The parameter \"%s\" declares values, but it is not an enum", param.Name)
+			}
+		case PARAM_TYPE_ENUM:
+			if len(param.Values) == 0 {
+				return newConfigError("The enum parameter \"%s\" has no values declared", param.Name)
+			}
+		default:
+			return newConfigError("The parameter \"%s\" has an unknown type \"%s\"", param.Name, param.Type)
+		}
+
+		if param.Default != nil {
+			if _, err := param.NormalizeValue(*param.Default); err != nil {
+				return newConfigError("The default value of the parameter \"%s\" is invalid: %s", param.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package cfg
+
+import (
+	"strings"
+	"syscall"
+)
+
+var signalsByName = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+}
+
+// ParseSignal returns the signal matching a name such as "SIGTERM" or "term"
+func ParseSignal(name string) (syscall.Signal, bool) {
+	signal, ok := signalsByName[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	return signal, ok
+}
+
+// SignalName returns the name of the signal, such as "SIGTERM"
+func SignalName(signal syscall.Signal) string {
+	for name, candidate := range signalsByName {
+		if candidate == signal {
+			return "SIG" + name
+		}
+	}
+
+	return signal.String()
+}
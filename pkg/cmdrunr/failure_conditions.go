@@ -0,0 +1,44 @@
+package cmdrunr
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/corentindeboisset/tera/pkg/cfg"
+)
+
+// Decides whether a command that exited with the given code is successful, using its success_exit_codes and its
+// failed_when conditions on the output. When the result differs from what the exit code alone would give,
+// the returned explanation tells why.
+func evaluateSuccess(config cfg.CmdConfig, exitCode int, output []byte) (bool, string) {
+	successCodes := config.SuccessExitCodes
+	if len(successCodes) == 0 {
+		successCodes = []int{0}
+	}
+
+	if !slices.Contains(successCodes, exitCode) {
+		if exitCode == 0 {
+			return false, "The command exited with the code 0, which is not declared in its success_exit_codes"
+		}
+		return false, ""
+	}
+
+	if len(config.FailedWhen.OutputMatches) > 0 {
+		// The regexes are validated with the configuration
+		if regexp.MustCompile(config.FailedWhen.OutputMatches).Match(output) {
+			return false, fmt.Sprintf("The command is considered failed because its output matches \"%s\"", config.FailedWhen.OutputMatches)
+		}
+	}
+	if len(config.FailedWhen.OutputNotMatches) > 0 {
+		if !regexp.MustCompile(config.FailedWhen.OutputNotMatches).Match(output) {
+			return false, fmt.Sprintf("The command is considered failed because its output does not match \"%s\"", config.FailedWhen.OutputNotMatches)
+		}
+	}
+
+	if exitCode != 0 {
+		return true, fmt.Sprintf("The command exited with the code %d, which is declared in its success_exit_codes", exitCode)
+	}
+
+	return true, ""
+}
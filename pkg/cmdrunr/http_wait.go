@@ -0,0 +1,92 @@
+package cmdrunr
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+)
+
+type HttpCheck struct {
+	Url    string
+	Method string
+
+	// If empty, any status between 200 and 399 is accepted
+	ExpectedStatus []int
+	// If not nil, the body of the response must match it
+	BodyRegex *regexp.Regexp
+	// Skip the verification of the TLS certificates
+	Insecure bool
+}
+
+func newHttpCheckClient(check HttpCheck) *http.Client {
+	return &http.Client{
+		Timeout: PROBE_ATTEMPT_TIMEOUT,
+		// The redirections are not followed, the status of the first response is the one that is checked
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: check.Insecure},
+		},
+	}
+}
+
+func WaitForHttp(ctx context.Context, check HttpCheck, timing ProbeTiming) bool {
+	client := newHttpCheckClient(check)
+	defer client.CloseIdleConnections()
+
+	return waitFor(ctx, timing, func(ctx context.Context) bool {
+		return checkHttp(ctx, client, check)
+	})
+}
+
+// Sends a single request, and checks its response
+func CheckHttp(ctx context.Context, check HttpCheck) bool {
+	client := newHttpCheckClient(check)
+	defer client.CloseIdleConnections()
+
+	return checkHttp(ctx, client, check)
+}
+
+func checkHttp(ctx context.Context, client *http.Client, check HttpCheck) bool {
+	method := check.Method
+	if len(method) == 0 {
+		method = http.MethodGet
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, check.Url, nil)
+	if err != nil {
+		log.Printf("Warning: the healthcheck request could not be created: %s", err)
+		return false
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if len(check.ExpectedStatus) > 0 {
+		if !slices.Contains(check.ExpectedStatus, response.StatusCode) {
+			return false
+		}
+	} else if response.StatusCode < 200 || response.StatusCode >= 400 {
+		return false
+	}
+
+	if check.BodyRegex != nil {
+		// Limit the size of the body that is read, healthcheck endpoints are not expected to be large
+		body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+		if err != nil {
+			return false
+		}
+
+		return check.BodyRegex.Match(body)
+	}
+
+	return true
+}
@@ -0,0 +1,43 @@
+package cmdrunr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckHttp(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ready":
+			_, _ = w.Write([]byte(`{"status": "ready"}`))
+		case "/starting":
+			_, _ = w.Write([]byte(`{"status": "starting"}`))
+		case "/redirect":
+			http.Redirect(w, r, "/ready", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := server.Client()
+	ctx := context.Background()
+
+	assert.True(t, checkHttp(ctx, client, HttpCheck{Url: server.URL + "/ready"}))
+	assert.False(t, checkHttp(ctx, client, HttpCheck{Url: server.URL + "/unavailable"}))
+	assert.True(t, checkHttp(ctx, client, HttpCheck{Url: server.URL + "/unavailable", ExpectedStatus: []int{503}}))
+	assert.False(t, checkHttp(ctx, client, HttpCheck{Url: server.URL + "/ready", ExpectedStatus: []int{204}}))
+
+	ready := regexp.MustCompile(`"status": "ready"`)
+	assert.True(t, checkHttp(ctx, client, HttpCheck{Url: server.URL + "/ready", BodyRegex: ready}))
+	assert.False(t, checkHttp(ctx, client, HttpCheck{Url: server.URL + "/starting", BodyRegex: ready}))
+
+	assert.False(t, checkHttp(ctx, client, HttpCheck{Url: "http://127.0.0.1:1/unreachable"}))
+}
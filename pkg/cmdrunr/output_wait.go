@@ -0,0 +1,33 @@
+package cmdrunr
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Waits for a line written in the output after the given offset to match the regex.
+// The ANSI escape sequences are removed from the lines before they are matched.
+func WaitForOutput(ctx context.Context, output *SafeBuffer, offset int, r *regexp.Regexp, timing ProbeTiming) bool {
+	var pending []byte
+
+	return waitFor(ctx, timing, func(ctx context.Context) bool {
+		newContent := output.BytesFrom(offset)
+		offset += len(newContent)
+		pending = append(pending, newContent...)
+
+		// Check the complete lines, and keep the last incomplete one for the next iteration.
+		// The incomplete line is checked too, since some programs print a prompt without a line return.
+		lines := bytes.Split(pending, []byte("\n"))
+		pending = lines[len(lines)-1]
+		for _, line := range lines {
+			if r.MatchString(ansi.Strip(string(line))) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
@@ -0,0 +1,39 @@
+package cmdrunr
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForOutput(t *testing.T) {
+	t.Parallel()
+
+	var output SafeBuffer
+	_, _ = output.Write([]byte("Compiled successfully in a previous run\n"))
+	offset := output.Len()
+
+	r := regexp.MustCompile(`^Compiled successfully`)
+	done := make(chan bool)
+	go func() {
+		done <- WaitForOutput(context.Background(), &output, offset, r, ProbeTiming{Interval: 50 * time.Millisecond})
+	}()
+
+	_, _ = output.Write([]byte("Compiling...\n\x1b[32mCompiled"))
+	select {
+	case <-done:
+		t.Fatal("The healthcheck should not have passed yet")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	_, _ = output.Write([]byte(" successfully\x1b[0m in 1.2s\n"))
+	assert.True(t, <-done)
+
+	// A cancelled context stops the wait
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, WaitForOutput(ctx, &output, output.Len(), r, ProbeTiming{}))
+}
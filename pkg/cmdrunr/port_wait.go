@@ -8,28 +8,20 @@ import (
 	"time"
 )
 
-func WaitForPort(ctx context.Context, port int) bool {
+func WaitForPort(ctx context.Context, port int, timing ProbeTiming) bool {
 	if port < 0 || port > 65535 {
 		// Invalid port is considered ok
 		log.Printf("Warning: an invalid port was specified")
 		return true
 	}
 
-	dialContext, cancel := context.WithTimeout(ctx, 5*time.Minute)
-	defer cancel()
-
-	for !checkPort(dialContext, port) {
-		select {
-		case <-dialContext.Done():
-			return false
-		case <-time.After(500 * time.Millisecond):
-		}
-	}
-
-	return true
+	return waitFor(ctx, timing, func(ctx context.Context) bool {
+		return CheckPort(ctx, port)
+	})
 }
 
-func checkPort(ctx context.Context, port int) bool {
+// Tries once to connect to the port on the local host
+func CheckPort(ctx context.Context, port int) bool {
 	d := net.Dialer{Timeout: 60 * time.Second}
 	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort("127.0.0.1", fmt.Sprintf("%d", port)))
 
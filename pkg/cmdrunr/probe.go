@@ -0,0 +1,82 @@
+package cmdrunr
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/corentindeboisset/tera/pkg/cfg"
+)
+
+const (
+	DEFAULT_PROBE_INTERVAL = 500 * time.Millisecond
+	DEFAULT_PROBE_TIMEOUT  = 5 * time.Minute
+
+	// Maximum duration of a single attempt of the probes that can hang (http requests, commands)
+	PROBE_ATTEMPT_TIMEOUT = 10 * time.Second
+)
+
+// The delay between two attempts of a probe, and the total duration after which it is considered failed.
+// The zero values are replaced by the defaults.
+type ProbeTiming struct {
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// Calls the probe until it succeeds, waiting for the interval between attempts.
+// Returns false if the timeout is reached or if the context is cancelled.
+func waitFor(ctx context.Context, timing ProbeTiming, probe func(ctx context.Context) bool) bool {
+	interval := timing.Interval
+	if interval <= 0 {
+		interval = DEFAULT_PROBE_INTERVAL
+	}
+	timeout := timing.Timeout
+	if timeout <= 0 {
+		timeout = DEFAULT_PROBE_TIMEOUT
+	}
+
+	waitContext, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for !probe(waitContext) {
+		select {
+		case <-waitContext.Done():
+			return false
+		case <-time.After(interval):
+		}
+	}
+
+	return true
+}
+
+// Waits for a file to exist. A relative path is resolved from the base path
+func WaitForFile(ctx context.Context, basePath, path string, timing ProbeTiming) bool {
+	return waitFor(ctx, timing, func(ctx context.Context) bool {
+		return CheckFile(basePath, path)
+	})
+}
+
+// Checks once that the file exists
+func CheckFile(basePath, path string) bool {
+	_, err := os.Stat(getCmdPath(basePath, path))
+	return err == nil
+}
+
+// Waits for a command to exit successfully. It is run like any other command, in the directory given by the paths and with the given environments
+func WaitForCmd(ctx context.Context, basePath, path, cmd string, env []cfg.EnvConfig, timing ProbeTiming) bool {
+	return waitFor(ctx, timing, func(ctx context.Context) bool {
+		return CheckCmd(ctx, basePath, path, cmd, env)
+	})
+}
+
+// Runs the command once, and returns whether it was successful.
+// A command stopped because the service is killed, or because the attempt took too long, is not successful.
+func CheckCmd(ctx context.Context, basePath, path, cmd string, env []cfg.EnvConfig) bool {
+	attemptContext, cancel := context.WithTimeout(ctx, PROBE_ATTEMPT_TIMEOUT)
+	defer cancel()
+
+	// The output of the probe is discarded, to avoid flooding the output of the service
+	var output SafeBuffer
+	success, stopped := RunCancellableCommand(attemptContext, basePath, cfg.CmdConfig{Cmd: cmd, Path: path}, env, &output, NewTerminalSize(80, 24))
+	return success && !stopped && attemptContext.Err() == nil
+}
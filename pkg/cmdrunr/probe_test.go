@@ -0,0 +1,56 @@
+package cmdrunr
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForFile(t *testing.T) {
+	t.Parallel()
+
+	basePath := t.TempDir()
+	timing := ProbeTiming{Interval: 10 * time.Millisecond, Timeout: 5 * time.Second}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = os.WriteFile(filepath.Join(basePath, "service.pid"), []byte("42"), 0644)
+	}()
+	assert.True(t, WaitForFile(context.Background(), basePath, "service.pid", timing))
+
+	shortTiming := ProbeTiming{Interval: 10 * time.Millisecond, Timeout: 50 * time.Millisecond}
+	assert.False(t, WaitForFile(context.Background(), basePath, "missing.sock", shortTiming))
+}
+
+func TestWaitForCmd(t *testing.T) {
+	t.Parallel()
+
+	basePath := t.TempDir()
+	timing := ProbeTiming{Interval: 10 * time.Millisecond, Timeout: 5 * time.Second}
+
+	// The command is run in the given directory, and succeeds once the file is created
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = os.WriteFile(filepath.Join(basePath, "ready"), nil, 0644)
+	}()
+	assert.True(t, WaitForCmd(context.Background(), basePath, ".", "test -f ready", nil, timing))
+
+	shortTiming := ProbeTiming{Interval: 10 * time.Millisecond, Timeout: 100 * time.Millisecond}
+	assert.False(t, WaitForCmd(context.Background(), basePath, ".", "exit 1", nil, shortTiming))
+}
+
+func TestCheckCmdKilled(t *testing.T) {
+	t.Parallel()
+
+	// A probe stopped because the service is killed does not pass
+	ctx, cancel := context.WithCancelCause(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel(ErrPlannedKill)
+	}()
+	assert.False(t, CheckCmd(ctx, t.TempDir(), ".", "sleep 5", nil))
+}
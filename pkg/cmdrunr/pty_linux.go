@@ -0,0 +1,67 @@
+package cmdrunr
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Opens a new pseudo-terminal, and returns its master and slave sides.
+// The slave side does not translate the line feeds into carriage returns and line feeds, to keep the output
+// as close as possible to the one of a pipe.
+func openPty() (*os.File, *os.File, error) {
+	// The master is non-blocking, so that its reads go through the runtime poller and are interrupted by Close
+	masterFd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	master := os.NewFile(uintptr(masterFd), "/dev/ptmx")
+
+	var slaveNumber uint32
+	if err := unix.IoctlSetPointerInt(masterFd, unix.TIOCSPTLCK, 0); err != nil {
+		_ = master.Close()
+		return nil, nil, err
+	}
+	if slaveNumber, err = unix.IoctlGetUint32(masterFd, unix.TIOCGPTN); err != nil {
+		_ = master.Close()
+		return nil, nil, err
+	}
+
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", slaveNumber), os.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		_ = master.Close()
+		return nil, nil, err
+	}
+
+	termios, err := unix.IoctlGetTermios(int(slave.Fd()), unix.TCGETS)
+	if err == nil {
+		termios.Oflag &^= unix.ONLCR
+		err = unix.IoctlSetTermios(int(slave.Fd()), unix.TCSETS, termios)
+	}
+	if err != nil {
+		_ = master.Close()
+		_ = slave.Close()
+		return nil, nil, err
+	}
+
+	return master, slave, nil
+}
+
+// Sets the size of the pseudo-terminal. The kernel sends a SIGWINCH to the processes running in it.
+func setPtySize(master *os.File, width, height int) error {
+	rawConn, err := master.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var ioctlErr error
+	err = rawConn.Control(func(fd uintptr) {
+		ioctlErr = unix.IoctlSetWinsize(int(fd), unix.TIOCSWINSZ, &unix.Winsize{Row: uint16(height), Col: uint16(width)})
+	})
+	if err != nil {
+		return err
+	}
+
+	return ioctlErr
+}
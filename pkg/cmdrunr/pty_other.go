@@ -0,0 +1,16 @@
+//go:build !linux
+
+package cmdrunr
+
+import (
+	"errors"
+	"os"
+)
+
+func openPty() (*os.File, *os.File, error) {
+	return nil, nil, errors.New("the pseudo-terminals are only supported on Linux")
+}
+
+func setPtySize(master *os.File, width, height int) error {
+	return nil
+}
@@ -5,14 +5,30 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"sync"
 	"syscall"
+	"time"
+
+	"github.com/corentindeboisset/tera/pkg/cfg"
+)
+
+var (
+	ErrPlannedKill    = errors.New("process planned to be killed")
+	ErrCommandTimeout = errors.New("the command timed out")
 )
 
-var ErrPlannedKill = errors.New("process planned to be killed")
+const (
+	DEFAULT_STOP_SIGNAL  = syscall.SIGTERM
+	DEFAULT_STOP_TIMEOUT = 10 * time.Second
+
+	// Duration during which the output of a pseudo-terminal is still read after the command exited
+	PTY_DRAIN_TIMEOUT = time.Second
+)
 
 type SafeBuffer struct {
 	buf bytes.Buffer
@@ -34,6 +50,35 @@ func (s *SafeBuffer) Bytes() []byte {
 	return output
 }
 
+func (s *SafeBuffer) Len() int {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.buf.Len()
+}
+
+// Empties the buffer
+func (s *SafeBuffer) Reset() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.buf.Reset()
+}
+
+// Returns a copy of the content written after the given offset
+func (s *SafeBuffer) BytesFrom(offset int) []byte {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if offset >= s.buf.Len() {
+		return nil
+	}
+
+	output := make([]byte, s.buf.Len()-offset)
+	_ = copy(output, s.buf.Bytes()[offset:])
+	return output
+}
+
 func getCmdPath(basePath, cmdPath string) string {
 	if filepath.IsAbs(cmdPath) {
 		return cmdPath
@@ -42,39 +87,186 @@ func getCmdPath(basePath, cmdPath string) string {
 	}
 }
 
-func RunCommand(ctx context.Context, basePath, path, cmd string, output *SafeBuffer, width, height int) bool {
+// Returns the line written in the output before the command starts
+func CommandHeader(cmd string) string {
+	return fmt.Sprintf("> %s\n", cmd)
+}
+
+// Returns the signal and the grace period used to stop the command, with the default values if they are not configured
+func getStopSettings(config cfg.CmdConfig) (syscall.Signal, time.Duration) {
+	stopSignal := DEFAULT_STOP_SIGNAL
+	if parsedSignal, ok := cfg.ParseSignal(config.StopSignal); ok {
+		stopSignal = parsedSignal
+	}
+	stopTimeout := DEFAULT_STOP_TIMEOUT
+	if config.StopTimeout > 0 {
+		stopTimeout = config.StopTimeout
+	}
+
+	return stopSignal, stopTimeout
+}
+
+// Runs the command and writes its output in the buffer, then returns whether it was successful.
+// The env argument lists the environments inherited by the command, by increasing precedence.
+// The environment declared on the command itself has the highest precedence.
+func RunCommand(ctx context.Context, basePath string, config cfg.CmdConfig, env []cfg.EnvConfig, output *SafeBuffer, width, height int) bool {
+	return RunCommandInTerminal(ctx, basePath, config, env, output, NewTerminalSize(width, height))
+}
+
+// Same as RunCommand, with a terminal size that can change while the command runs
+func RunCommandInTerminal(ctx context.Context, basePath string, config cfg.CmdConfig, env []cfg.EnvConfig, output *SafeBuffer, size *TerminalSize) bool {
+	success, _ := RunCancellableCommand(ctx, basePath, config, env, output, size)
+	return success
+}
+
+// Same as RunCommandInTerminal, and also returns whether the command was stopped because its context was cancelled.
+// A command that exited by itself before the cancellation, or that reached its timeout, is not considered as stopped.
+func RunCancellableCommand(ctx context.Context, basePath string, config cfg.CmdConfig, env []cfg.EnvConfig, output *SafeBuffer, size *TerminalSize) (bool, bool) {
 	// Note: this only works on Unix platforms
 	// TODO: maybe add support for windows (or not... :shrug:)
-	_, _ = fmt.Fprintf(output, "> %s\n", cmd)
-	task := exec.CommandContext(ctx, "/bin/sh", "-c", cmd)
-	task.Dir = getCmdPath(basePath, path)
+	_, _ = fmt.Fprint(output, CommandHeader(config.Cmd))
 
-	// Pass the environment to the child processes, and set the WIDTH/HEIGHT env variables
-	task.Env = append(os.Environ(), fmt.Sprintf("COLUMNS=%d", width), fmt.Sprintf("LINES=%d", height))
+	resolvedEnv, err := cfg.ResolveEnv(basePath, append(slices.Clone(env), config.EnvConfig)...)
+	if err != nil {
+		_, _ = fmt.Fprintf(output, "\n\nThe environment of the command could not be loaded:\n%s", err.Error())
+		return false, false
+	}
+
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, config.Timeout, ErrCommandTimeout)
+		defer cancel()
+	}
+
+	// Only the output of this command is checked by its failed_when conditions
+	outputStart := output.Len()
+
+	task := exec.CommandContext(ctx, "/bin/sh", "-c", config.Cmd)
+	task.Dir = getCmdPath(basePath, config.Path)
+
+	// Pass the environment to the child processes, along with the additional variables, and set the WIDTH/HEIGHT env variables
+	task.Env = append(os.Environ(), resolvedEnv...)
+	width, height := size.Get()
+	task.Env = append(task.Env, fmt.Sprintf("COLUMNS=%d", width), fmt.Sprintf("LINES=%d", height))
 	task.Stdout = output
 	task.Stderr = output
 	task.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	// With tty, the command runs in a pseudo-terminal of the size of the output, so that the tools keep their colors
+	// and their progress displays. It runs in its own session, whose process group is the one stopped on cancellation.
+	var ptyMaster, ptySlave *os.File
+	if config.Tty {
+		ptyMaster, ptySlave, err = openPty()
+		if err != nil {
+			_, _ = fmt.Fprintf(output, "The command cannot run in a terminal, it runs without one: %s\n", err.Error())
+		} else {
+			defer ptyMaster.Close()
+			stopFollowingSize := size.onResize(func(width, height int) {
+				_ = setPtySize(ptyMaster, width, height)
+			})
+			defer stopFollowingSize()
+			_ = setPtySize(ptyMaster, width, height)
+			task.Stdin, task.Stdout, task.Stderr = ptySlave, ptySlave, ptySlave
+			task.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+		}
+	}
+
+	// When the context is cancelled, the stop signal is sent to the whole process group.
+	// If the processes are still there after the grace period, they are killed.
+	stopSignal, stopTimeout := getStopSettings(config)
+	taskDone := make(chan struct{})
+	cancelled := false
 	task.Cancel = func() error {
-		return syscall.Kill(-task.Process.Pid, syscall.SIGKILL)
+		// The cancel function is only called while the command is running, and Wait returns after it
+		cancelled = true
+		processGroup := -task.Process.Pid
+		if stopSignal == syscall.SIGKILL {
+			return syscall.Kill(processGroup, syscall.SIGKILL)
+		}
+
+		if err := syscall.Kill(processGroup, stopSignal); err != nil {
+			return err
+		}
+
+		go func() {
+			select {
+			case <-taskDone:
+			case <-time.After(stopTimeout):
+				_, _ = fmt.Fprintf(output, "\nThe command was still running %s after receiving %s, it is killed\n", stopTimeout, cfg.SignalName(stopSignal))
+				_ = syscall.Kill(processGroup, syscall.SIGKILL)
+			}
+		}()
+
+		return nil
 	}
 
-	if err := task.Start(); err != nil {
+	err = task.Start()
+	if ptySlave != nil {
+		// The slave side now belongs to the command
+		_ = ptySlave.Close()
+	}
+	if err != nil {
 		_, _ = fmt.Fprintf(output, "\n\nThe command could not start due to the following error:\n%s", err.Error())
-		return false
+		return false, false
 	}
 
-	err := task.Wait()
+	ptyDrained := make(chan struct{})
+	if ptyMaster != nil {
+		go func() {
+			// The copy ends with an error once all the processes closed the terminal
+			_, _ = io.Copy(output, ptyMaster)
+			close(ptyDrained)
+		}()
+	}
+
+	err = task.Wait()
+	close(taskDone)
+
+	if ptyMaster != nil {
+		// Processes left in the background may keep the terminal open: the output is not read after a delay
+		select {
+		case <-ptyDrained:
+		case <-time.After(PTY_DRAIN_TIMEOUT):
+			_ = ptyMaster.Close()
+			<-ptyDrained
+		}
+	}
+
+	// A command stopped by its timeout failed, even if it handled the stop signal and exited successfully
+	if cancelled && errors.Is(context.Cause(ctx), ErrCommandTimeout) {
+		_, _ = fmt.Fprintf(output, "\n\nThe command timed out after %s\n", config.Timeout)
+		return false, false
+	}
+
+	if err != nil && errors.Is(context.Cause(ctx), ErrPlannedKill) {
+		_, _ = fmt.Fprintf(output, "\nService killed\n\n")
+		return true, cancelled
+	}
+
+	exitCode := 0
 	if err != nil {
-		if errors.Is(context.Cause(ctx), ErrPlannedKill) {
-			_, _ = fmt.Fprintf(output, "\nService killed\n\n")
-			return true
-		} else {
+		exitErr, ok := errors.AsType[*exec.ExitError](err)
+		if !ok || !exitErr.Exited() {
 			_, _ = fmt.Fprintf(output, "\n\nThe command failed with the following error:\n%s", err.Error())
-			return false
+			return false, cancelled
 		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	// The exit code may be overridden by the success_exit_codes and the failed_when conditions
+	success, explanation := evaluateSuccess(config, exitCode, output.BytesFrom(outputStart))
+	if !success {
+		if len(explanation) == 0 {
+			explanation = fmt.Sprintf("The command failed with the following error:\n%s", err.Error())
+		}
+		_, _ = fmt.Fprintf(output, "\n\n%s", explanation)
+		return false, cancelled
+	}
+	if len(explanation) > 0 {
+		_, _ = fmt.Fprintf(output, "\n%s\n", explanation)
 	}
 
 	_, _ = fmt.Fprintf(output, "\n\n")
 
-	return true
+	return true, cancelled
 }
@@ -0,0 +1,204 @@
+package cmdrunr
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corentindeboisset/tera/pkg/cfg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGracefulStop(t *testing.T) {
+	t.Parallel()
+
+	// The command handles the stop signal and exits by itself
+	var output SafeBuffer
+	ctx, cancel := context.WithCancelCause(context.Background())
+	config := cfg.CmdConfig{
+		Cmd:         "trap 'echo graceful exit; exit 0' INT; echo started; while true; do sleep 0.05; done",
+		StopSignal:  "SIGINT",
+		StopTimeout: 5 * time.Second,
+	}
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cancel(ErrPlannedKill)
+	}()
+	assert.True(t, RunCommand(ctx, t.TempDir(), config, nil, &output, 80, 24))
+	assert.Contains(t, string(output.Bytes()), "graceful exit")
+	assert.NotContains(t, string(output.Bytes()), "it is killed")
+
+	// The command ignores the stop signal, and is killed after the timeout
+	var stubbornOutput SafeBuffer
+	ctx, cancel = context.WithCancelCause(context.Background())
+	config = cfg.CmdConfig{
+		Cmd:         "trap '' TERM; echo started; while true; do sleep 0.05; done",
+		StopTimeout: 100 * time.Millisecond,
+	}
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cancel(ErrPlannedKill)
+	}()
+	start := time.Now()
+	assert.True(t, RunCommand(ctx, t.TempDir(), config, nil, &stubbornOutput, 80, 24))
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Contains(t, string(stubbornOutput.Bytes()), "The command was still running 100ms after receiving SIGTERM, it is killed")
+}
+
+func TestCancellableCommand(t *testing.T) {
+	t.Parallel()
+
+	// The command fails by itself before the cancellation
+	var failedOutput SafeBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	success, stopped := RunCancellableCommand(ctx, t.TempDir(), cfg.CmdConfig{Cmd: "exit 2"}, nil, &failedOutput, NewTerminalSize(80, 24))
+	cancel()
+	assert.False(t, success)
+	assert.False(t, stopped)
+
+	// The command is still running when the context is cancelled
+	var stoppedOutput SafeBuffer
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+	}()
+	success, stopped = RunCancellableCommand(ctx, t.TempDir(), cfg.CmdConfig{Cmd: "sleep 10"}, nil, &stoppedOutput, NewTerminalSize(80, 24))
+	assert.False(t, success)
+	assert.True(t, stopped)
+
+	// A timeout is a failure of the command itself
+	var timeoutOutput SafeBuffer
+	config := cfg.CmdConfig{Cmd: "sleep 10", Timeout: 100 * time.Millisecond}
+	success, stopped = RunCancellableCommand(context.Background(), t.TempDir(), config, nil, &timeoutOutput, NewTerminalSize(80, 24))
+	assert.False(t, success)
+	assert.False(t, stopped)
+}
+
+func TestCommandTimeout(t *testing.T) {
+	t.Parallel()
+
+	var output SafeBuffer
+	config := cfg.CmdConfig{
+		Cmd:     "echo started; sleep 10",
+		Timeout: 200 * time.Millisecond,
+	}
+	start := time.Now()
+	assert.False(t, RunCommand(context.Background(), t.TempDir(), config, nil, &output, 80, 24))
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Contains(t, string(output.Bytes()), "The command timed out after 200ms")
+
+	var fastOutput SafeBuffer
+	config = cfg.CmdConfig{
+		Cmd:     "echo fast",
+		Timeout: 5 * time.Second,
+	}
+	assert.True(t, RunCommand(context.Background(), t.TempDir(), config, nil, &fastOutput, 80, 24))
+	assert.NotContains(t, string(fastOutput.Bytes()), "timed out")
+
+	// The command handles the stop signal and exits successfully, but it still timed out
+	var gracefulOutput SafeBuffer
+	config = cfg.CmdConfig{
+		Cmd:     "trap 'echo graceful exit; exit 0' TERM; echo started; while true; do sleep 0.05; done",
+		Timeout: 200 * time.Millisecond,
+	}
+	assert.False(t, RunCommand(context.Background(), t.TempDir(), config, nil, &gracefulOutput, 80, 24))
+	assert.Contains(t, string(gracefulOutput.Bytes()), "graceful exit")
+	assert.Contains(t, string(gracefulOutput.Bytes()), "The command timed out after 200ms")
+}
+
+func TestFailureConditions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		config      cfg.CmdConfig
+		success     bool
+		explanation string
+	}{
+		{
+			config:  cfg.CmdConfig{Cmd: "exit 0"},
+			success: true,
+		},
+		{
+			config:      cfg.CmdConfig{Cmd: "echo 'warnings found'; exit 2", SuccessExitCodes: []int{0, 2}},
+			success:     true,
+			explanation: "The command exited with the code 2, which is declared in its success_exit_codes",
+		},
+		{
+			config:      cfg.CmdConfig{Cmd: "exit 0", SuccessExitCodes: []int{1}},
+			success:     false,
+			explanation: "The command exited with the code 0, which is not declared in its success_exit_codes",
+		},
+		{
+			config:      cfg.CmdConfig{Cmd: "echo 'FATAL: no database'", FailedWhen: cfg.FailedWhenConfig{OutputMatches: "FATAL|ERROR"}},
+			success:     false,
+			explanation: "The command is considered failed because its output matches \"FATAL|ERROR\"",
+		},
+		{
+			config:      cfg.CmdConfig{Cmd: "echo '3 tests run'", FailedWhen: cfg.FailedWhenConfig{OutputNotMatches: "All tests passed"}},
+			success:     false,
+			explanation: "The command is considered failed because its output does not match \"All tests passed\"",
+		},
+		{
+			config:  cfg.CmdConfig{Cmd: "echo 'All tests passed'", FailedWhen: cfg.FailedWhenConfig{OutputNotMatches: "All tests passed"}},
+			success: true,
+		},
+		{
+			config:      cfg.CmdConfig{Cmd: "echo 'ERROR'; exit 3", FailedWhen: cfg.FailedWhenConfig{OutputMatches: "ERROR"}},
+			success:     false,
+			explanation: "exit status 3",
+		},
+	}
+
+	for _, testCase := range testCases {
+		var output SafeBuffer
+		// The output written before the command, like its header, is not checked by the conditions
+		_, _ = fmt.Fprintf(&output, "FATAL|ERROR All tests passed\n")
+
+		assert.Equal(t, testCase.success, RunCommand(context.Background(), t.TempDir(), testCase.config, nil, &output, 80, 24), testCase.config.Cmd)
+		if len(testCase.explanation) > 0 {
+			assert.Contains(t, string(output.Bytes()), testCase.explanation, testCase.config.Cmd)
+		}
+	}
+}
+
+func TestPseudoTerminal(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("the pseudo-terminals are only supported on Linux")
+	}
+
+	// Without tty, the command writes in a pipe
+	var pipeOutput SafeBuffer
+	config := cfg.CmdConfig{Cmd: "if [ -t 1 ]; then echo terminal; else echo pipe; fi"}
+	assert.True(t, RunCommand(context.Background(), t.TempDir(), config, nil, &pipeOutput, 80, 24))
+	assert.Contains(t, string(pipeOutput.Bytes()), "pipe\n")
+
+	var ttyOutput SafeBuffer
+	config = cfg.CmdConfig{Cmd: "if [ -t 1 ]; then echo terminal; else echo pipe; fi; stty size", Tty: true}
+	assert.True(t, RunCommand(context.Background(), t.TempDir(), config, nil, &ttyOutput, 80, 24))
+	assert.Contains(t, string(ttyOutput.Bytes()), "terminal\n24 80\n")
+
+	// The size of the terminal follows the resizes while the command runs
+	var resizedOutput SafeBuffer
+	size := NewTerminalSize(80, 24)
+	config = cfg.CmdConfig{Cmd: "echo ready; while [ \"$(stty size)\" != '30 100' ]; do sleep 0.05; done; echo resized", Tty: true, Timeout: 5 * time.Second}
+	go func() {
+		for !strings.Contains(string(resizedOutput.Bytes()), "\nready\n") {
+			time.Sleep(10 * time.Millisecond)
+		}
+		size.Resize(100, 30)
+	}()
+	assert.True(t, RunCommandInTerminal(context.Background(), t.TempDir(), config, nil, &resizedOutput, size))
+	assert.Contains(t, string(resizedOutput.Bytes()), "resized\n")
+
+	// A failure is still reported
+	var failureOutput SafeBuffer
+	config = cfg.CmdConfig{Cmd: "echo failing; exit 4", Tty: true}
+	assert.False(t, RunCommand(context.Background(), t.TempDir(), config, nil, &failureOutput, 80, 24))
+	assert.Contains(t, string(failureOutput.Bytes()), "exit status 4")
+}
@@ -0,0 +1,65 @@
+package cmdrunr
+
+import "sync"
+
+// Size of the terminal in which the commands are displayed, given to the commands when they start.
+// It can change while they run: the commands that run in a pseudo-terminal are notified of the new size.
+type TerminalSize struct {
+	width  int
+	height int
+
+	listeners      map[int]func(width, height int)
+	nextListenerId int
+	mtx            sync.Mutex
+}
+
+func NewTerminalSize(width, height int) *TerminalSize {
+	return &TerminalSize{
+		width:     width,
+		height:    height,
+		listeners: make(map[int]func(width, height int)),
+	}
+}
+
+func (t *TerminalSize) Get() (int, int) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	return t.width, t.height
+}
+
+// Changes the size, and notifies the commands that are running
+func (t *TerminalSize) Resize(width, height int) {
+	t.mtx.Lock()
+	if t.width == width && t.height == height {
+		t.mtx.Unlock()
+		return
+	}
+	t.width, t.height = width, height
+	listeners := make([]func(width, height int), 0, len(t.listeners))
+	for _, listener := range t.listeners {
+		listeners = append(listeners, listener)
+	}
+	t.mtx.Unlock()
+
+	for _, listener := range listeners {
+		listener(width, height)
+	}
+}
+
+// Registers a function called on each resize, until the returned function is called
+func (t *TerminalSize) onResize(listener func(width, height int)) func() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	listenerId := t.nextListenerId
+	t.nextListenerId++
+	t.listeners[listenerId] = listener
+
+	return func() {
+		t.mtx.Lock()
+		defer t.mtx.Unlock()
+
+		delete(t.listeners, listenerId)
+	}
+}
@@ -0,0 +1,100 @@
+package jobexec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/corentindeboisset/tera/pkg/cfg"
+	"github.com/corentindeboisset/tera/pkg/cmdrunr"
+)
+
+// Evaluates the conditions of a step or a task, and returns whether it must be run.
+// When it must be skipped, the reason is returned too.
+func evaluateConditions(ctx context.Context, basePath string, env []cfg.EnvConfig, params []cfg.ParamConfig, conditions cfg.ConditionsConfig) (bool, string) {
+	if !conditions.HasConditions() {
+		return true, ""
+	}
+
+	resolvedEnv, err := cfg.ResolveEnv(basePath, env...)
+	if err != nil {
+		// The commands cannot be run with this environment either: they are run to report the error in their output
+		return true, ""
+	}
+	envValues := make(map[string]string, len(resolvedEnv))
+	for _, entry := range resolvedEnv {
+		name, value, _ := strings.Cut(entry, "=")
+		envValues[name] = value
+	}
+
+	if conditions.When != nil {
+		if holds, reason := checkCondition(ctx, basePath, env, envValues, params, conditions.When); !holds {
+			return false, fmt.Sprintf("the when condition does not hold: %s", reason)
+		}
+	}
+	if conditions.SkipIf != nil {
+		if holds, _ := checkCondition(ctx, basePath, env, envValues, params, conditions.SkipIf); holds {
+			return false, "the skip_if condition holds"
+		}
+	}
+
+	return true, ""
+}
+
+// Returns whether all the checks of the condition hold, or the description of the first one that does not
+func checkCondition(ctx context.Context, basePath string, env []cfg.EnvConfig, envValues map[string]string, params []cfg.ParamConfig, condition *cfg.ConditionConfig) (bool, string) {
+	lookupEnv := func(name string) string {
+		if value, ok := envValues[name]; ok {
+			return value
+		}
+		return os.Getenv(name)
+	}
+
+	if len(condition.Env) > 0 {
+		name, expected, hasExpected := strings.Cut(condition.Env, "=")
+		value := lookupEnv(name)
+		if hasExpected && value != expected {
+			return false, fmt.Sprintf("the variable %s is not \"%s\"", name, expected)
+		}
+		if !hasExpected && len(value) == 0 {
+			return false, fmt.Sprintf("the variable %s is not set", name)
+		}
+	}
+
+	if len(condition.File) > 0 {
+		path := condition.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(basePath, path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return false, fmt.Sprintf("the file \"%s\" does not exist", condition.File)
+		}
+	}
+
+	for name, expected := range condition.Params {
+		paramIdx := slices.IndexFunc(params, func(param cfg.ParamConfig) bool {
+			return param.Name == name
+		})
+		if paramIdx < 0 {
+			return false, fmt.Sprintf("the job has no parameter named \"%s\"", name)
+		}
+		// The expected values are validated with the configuration
+		normalized, _ := params[paramIdx].NormalizeValue(expected)
+		if lookupEnv(params[paramIdx].EnvName()) != normalized {
+			return false, fmt.Sprintf("the parameter %s is not \"%s\"", name, expected)
+		}
+	}
+
+	if len(condition.Cmd) > 0 {
+		// The output of the command is not displayed, only its result matters
+		var output cmdrunr.SafeBuffer
+		if !cmdrunr.RunCommand(ctx, basePath, cfg.CmdConfig{Cmd: condition.Cmd, Path: condition.Path}, env, &output, DEFAULT_OUTPUT_WIDTH, DEFAULT_OUTPUT_HEIGHT) {
+			return false, fmt.Sprintf("the command \"%s\" failed", condition.Cmd)
+		}
+	}
+
+	return true, ""
+}
@@ -34,17 +34,21 @@ var (
 	successFlag  = lipgloss.NewStyle().SetString("✓").Bold(true).Foreground(lipgloss.Color("082"))
 	spinnerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("226"))
 	failureFlag  = lipgloss.NewStyle().SetString("✗").Bold(true).Foreground(lipgloss.Color("196"))
+	skippedFlag  = lipgloss.NewStyle().SetString("↷").Bold(true).Foreground(lipgloss.Color("244"))
+	cachedFlag   = lipgloss.NewStyle().SetString("≡").Bold(true).Foreground(lipgloss.Color("082"))
+	waitingStyle = lipgloss.NewStyle().Faint(true).Inline(true)
 )
 
 type RefreshStatusMsg time.Time
 
 type keymap = struct {
-	up, down, tab, enter, quit key.Binding
+	up, down, tab, enter, previous, quit key.Binding
 }
 
 type registeredTask struct {
-	Name   string
-	Output *cmdrunr.SafeBuffer
+	Name           string
+	Output         *cmdrunr.SafeBuffer
+	PreviousOutput *cmdrunr.SafeBuffer
 }
 
 type ifaceModel struct {
@@ -56,6 +60,8 @@ type ifaceModel struct {
 	hideOutputPanel bool
 
 	focusOutput  bool
+	showPrevious bool
+	watching     bool
 	selectedTask int
 	focusedTask  int
 	spinner      spinner.Model
@@ -63,6 +69,7 @@ type ifaceModel struct {
 	outputPanel  viewport.Model
 
 	jobConfig *cfg.JobConfig
+	jobStatus *JobStatus
 	statuses  []StepStatus
 	taskIds   []registeredTask
 }
@@ -73,7 +80,8 @@ func tickReadOutputsMsg() tea.Cmd {
 	})
 }
 
-func newModel(config *cfg.JobConfig, statuses []StepStatus) ifaceModel {
+// Creates the interface of the job. In watch mode, the output of the previous run of each task can be displayed.
+func newModel(config *cfg.JobConfig, jobStatus *JobStatus, watching bool) ifaceModel {
 	m := ifaceModel{
 		help: help.New(),
 		keymap: keymap{
@@ -93,6 +101,10 @@ func newModel(config *cfg.JobConfig, statuses []StepStatus) ifaceModel {
 				key.WithKeys("enter"),
 				key.WithHelp("↵/Enter", "Select a task to display"),
 			),
+			previous: key.NewBinding(
+				key.WithKeys("p"),
+				key.WithHelp("p      ", "Toggle the previous run"),
+			),
 			quit: key.NewBinding(
 				key.WithKeys("ctrl+c"),
 				key.WithHelp("Ctrl+C ", "Exit"),
@@ -100,9 +112,11 @@ func newModel(config *cfg.JobConfig, statuses []StepStatus) ifaceModel {
 		},
 		focusOutput:     false,
 		hideOutputPanel: false,
+		watching:        watching,
 		jobConfig:       config,
 		stepPanelWidth:  15,
-		statuses:        statuses,
+		jobStatus:       jobStatus,
+		statuses:        jobStatus.Steps,
 		spinner: spinner.New(
 			spinner.WithSpinner(spinner.Dot),
 			spinner.WithStyle(spinnerStyle),
@@ -127,19 +141,25 @@ func (m *ifaceModel) initializeTaskOutputs() {
 		m.statuses[stepIdx].Mtx.Lock()
 		if m.statuses[stepIdx].BeforeHooks != nil {
 			id := fmt.Sprintf("step#%d__bh", stepIdx)
-			m.taskIds = append(m.taskIds, registeredTask{Name: id, Output: &m.statuses[stepIdx].BeforeHooks.Output})
+			m.taskIds = append(m.taskIds, registeredTask{Name: id, Output: &m.statuses[stepIdx].BeforeHooks.Output, PreviousOutput: &m.statuses[stepIdx].BeforeHooks.PreviousOutput})
 		}
 		for taskIdx := range m.statuses[stepIdx].Tasks {
 			id := fmt.Sprintf("step#%d__task#%d", stepIdx, taskIdx)
-			m.taskIds = append(m.taskIds, registeredTask{Name: id, Output: &m.statuses[stepIdx].Tasks[taskIdx].Output})
+			m.taskIds = append(m.taskIds, registeredTask{Name: id, Output: &m.statuses[stepIdx].Tasks[taskIdx].Output, PreviousOutput: &m.statuses[stepIdx].Tasks[taskIdx].PreviousOutput})
 		}
 		if m.statuses[stepIdx].AfterHooks != nil {
 			id := fmt.Sprintf("step#%d__ah", stepIdx)
-			m.taskIds = append(m.taskIds, registeredTask{Name: id, Output: &m.statuses[stepIdx].AfterHooks.Output})
+			m.taskIds = append(m.taskIds, registeredTask{Name: id, Output: &m.statuses[stepIdx].AfterHooks.Output, PreviousOutput: &m.statuses[stepIdx].AfterHooks.PreviousOutput})
 		}
 		m.statuses[stepIdx].Mtx.Unlock()
 	}
 
+	m.jobStatus.Mtx.Lock()
+	if m.jobStatus.AfterHooks != nil {
+		m.taskIds = append(m.taskIds, registeredTask{Name: "job__ah", Output: &m.jobStatus.AfterHooks.Output, PreviousOutput: &m.jobStatus.AfterHooks.PreviousOutput})
+	}
+	m.jobStatus.Mtx.Unlock()
+
 	// If the first task is a before_run hook, select the next task
 	if len(m.taskIds) > 2 && strings.HasSuffix(m.taskIds[0].Name, "__bh") {
 		m.selectedTask += 1
@@ -165,6 +185,9 @@ func (m *ifaceModel) calculateMinPanelSize() {
 			maxLen = 18
 		}
 	}
+	if len(m.jobConfig.RunAfter) > 0 && maxLen < 22 {
+		maxLen = 22
+	}
 
 	m.stepPanelWidth = maxLen + 10
 }
@@ -187,11 +210,18 @@ func (m *ifaceModel) updateSizes() {
 	}
 	m.outputPanel.Width = outputWidth
 	m.stepPanel.Resize(stepPanelWidth, panelsHeight)
+
+	// The commands get the size of the content of the output panel, and the ones in a terminal follow its resizes
+	m.jobStatus.OutputSize.Resize(
+		max(outputWidth-m.outputPanel.Style.GetHorizontalFrameSize(), 1),
+		max(panelsHeight-m.outputPanel.Style.GetVerticalFrameSize(), 1),
+	)
 }
 
 func (m *ifaceModel) updateKeyBindings() {
 	m.keymap.tab.SetEnabled(!m.hideOutputPanel)
 	m.keymap.enter.SetEnabled(!m.focusOutput && !m.hideOutputPanel)
+	m.keymap.previous.SetEnabled(m.watching && !m.hideOutputPanel)
 }
 
 func (m ifaceModel) Init() tea.Cmd {
@@ -219,18 +249,61 @@ func (m *ifaceModel) formatTask(id string, state TaskState, name string) string
 		return fmt.Sprintf("%s  %s", successFlag, name)
 	case STATE_FAILED:
 		return fmt.Sprintf("%s  %s", failureFlag, name)
+	case STATE_SKIPPED:
+		return fmt.Sprintf("%s  %s", skippedFlag, name)
+	case STATE_CACHED:
+		return fmt.Sprintf("%s  %s %s", cachedFlag, name, waitingStyle.Render("(up to date)"))
 	}
 
 	return ""
 }
 
+// Returns the attempt of a task that is retried, to display next to its name
+func formatAttempt(taskStatus *TaskStatus) string {
+	if taskStatus.MaxAttempts <= 1 || taskStatus.Attempt == 0 {
+		return ""
+	}
+
+	return " " + waitingStyle.Render(fmt.Sprintf("(attempt %d/%d)", taskStatus.Attempt, taskStatus.MaxAttempts))
+}
+
+// Returns the list of the needs that are not successful yet, to display what a task is waiting on
+func formatPendingNeeds(needs []string, isSuccessful func(name string) bool) string {
+	pending := make([]string, 0, len(needs))
+	for _, need := range needs {
+		if !isSuccessful(need) {
+			pending = append(pending, need)
+		}
+	}
+	if len(pending) == 0 {
+		return ""
+	}
+
+	return " " + waitingStyle.Render(fmt.Sprintf("(waits for %s)", strings.Join(pending, ", ")))
+}
+
 func (m *ifaceModel) calculateStepPanelContent() []ListViewportLine {
+	// The states of the steps are read first, to display the needs of each step
+	stepStates := make(map[string]TaskState, len(m.statuses))
+	for stepIdx, stepConfig := range m.jobConfig.Steps {
+		m.statuses[stepIdx].Mtx.Lock()
+		stepStates[stepConfig.Name] = m.statuses[stepIdx].state
+		m.statuses[stepIdx].Mtx.Unlock()
+	}
+	isStepSuccessful := func(name string) bool {
+		return stepStates[name] == STATE_SUCCESSFUL || stepStates[name] == STATE_SKIPPED
+	}
+
 	viewportLines := make([]ListViewportLine, 0, len(m.statuses)*2+len(m.taskIds))
 	for stepIdx, stepConfig := range m.jobConfig.Steps {
 		m.statuses[stepIdx].Mtx.Lock()
+		stepLine := m.formatTask("", m.statuses[stepIdx].state, stepConfig.Name)
+		if m.statuses[stepIdx].state == STATE_NOT_STARTED {
+			stepLine += formatPendingNeeds(stepConfig.Needs, isStepSuccessful)
+		}
 		viewportLines = append(viewportLines, ListViewportLine{
 			Padding: 0,
-			Content: m.formatTask("", m.statuses[stepIdx].state, stepConfig.Name),
+			Content: stepLine,
 		})
 		if m.statuses[stepIdx].BeforeHooks != nil {
 			id := fmt.Sprintf("step#%d__bh", stepIdx)
@@ -239,11 +312,27 @@ func (m *ifaceModel) calculateStepPanelContent() []ListViewportLine {
 				Content: m.formatTask(id, m.statuses[stepIdx].BeforeHooks.state, "Run-Before hooks"),
 			})
 		}
+		isTaskSuccessful := func(name string) bool {
+			for taskIdx, taskConfig := range stepConfig.Tasks {
+				if taskConfig.Name == name {
+					taskState := m.statuses[stepIdx].Tasks[taskIdx].state
+					return taskState == STATE_SUCCESSFUL || taskState == STATE_SKIPPED || taskState == STATE_CACHED
+				}
+			}
+			return false
+		}
 		for taskIdx, taskConfig := range stepConfig.Tasks {
 			id := fmt.Sprintf("step#%d__task#%d", stepIdx, taskIdx)
+			taskLine := m.formatTask(id, m.statuses[stepIdx].Tasks[taskIdx].state, taskConfig.Name)
+			taskLine += formatAttempt(&m.statuses[stepIdx].Tasks[taskIdx])
+			if m.statuses[stepIdx].Tasks[taskIdx].state == STATE_NOT_STARTED {
+				taskLine += formatPendingNeeds(taskConfig.Needs, isTaskSuccessful)
+			} else if m.statuses[stepIdx].Tasks[taskIdx].ErrorIgnored {
+				taskLine += " " + waitingStyle.Render("(error ignored)")
+			}
 			viewportLines = append(viewportLines, ListViewportLine{
 				Padding: 2,
-				Content: m.formatTask(id, m.statuses[stepIdx].Tasks[taskIdx].state, taskConfig.Name),
+				Content: taskLine,
 			})
 		}
 		if m.statuses[stepIdx].AfterHooks != nil {
@@ -257,6 +346,15 @@ func (m *ifaceModel) calculateStepPanelContent() []ListViewportLine {
 		m.statuses[stepIdx].Mtx.Unlock()
 	}
 
+	m.jobStatus.Mtx.Lock()
+	if m.jobStatus.AfterHooks != nil {
+		viewportLines = append(viewportLines, ListViewportLine{
+			Padding: 0,
+			Content: m.formatTask("job__ah", m.jobStatus.AfterHooks.state, "Job Run-After hooks"),
+		})
+	}
+	m.jobStatus.Mtx.Unlock()
+
 	return viewportLines
 }
 
@@ -297,6 +395,25 @@ func (m *ifaceModel) calculateTaskLine(taskIdx int) int {
 	return viewportOffset
 }
 
+// Displays the output of the selected task, or the output of its previous run if it is toggled
+func (m *ifaceModel) refreshOutputPanel() {
+	if m.hideOutputPanel {
+		return
+	}
+
+	output := string(m.taskIds[m.selectedTask].Output.Bytes())
+	if m.showPrevious {
+		output = waitingStyle.Render("Output of the previous run:") + "\n\n" + string(m.taskIds[m.selectedTask].PreviousOutput.Bytes())
+	}
+
+	isAtBottom := m.outputPanel.AtBottom()
+	contentWidth := m.outputPanel.Width - m.outputPanel.Style.GetHorizontalFrameSize()
+	m.outputPanel.SetContent(lipgloss.NewStyle().Width(contentWidth).Render(output))
+	if isAtBottom {
+		m.outputPanel.GotoBottom()
+	}
+}
+
 func (m ifaceModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
@@ -380,6 +497,12 @@ func (m ifaceModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if !m.focusOutput {
 				m.selectedTask = m.focusedTask
 			}
+
+		case "p":
+			if m.watching {
+				m.showPrevious = !m.showPrevious
+				m.refreshOutputPanel()
+			}
 		}
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -390,16 +513,7 @@ func (m ifaceModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.ClearScreen
 
 	case RefreshStatusMsg:
-		if !m.hideOutputPanel {
-			isAtBottom := m.outputPanel.AtBottom()
-			contentWidth := m.outputPanel.Width - m.outputPanel.Style.GetHorizontalFrameSize()
-			m.outputPanel.SetContent(
-				lipgloss.NewStyle().Width(contentWidth).Render(string(m.taskIds[m.selectedTask].Output.Bytes())),
-			)
-			if isAtBottom {
-				m.outputPanel.GotoBottom()
-			}
-		}
+		m.refreshOutputPanel()
 		return m, tickReadOutputsMsg()
 	case spinner.TickMsg:
 		var cmd tea.Cmd
@@ -435,7 +549,7 @@ func (m ifaceModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m ifaceModel) View() string {
 	help := m.help.FullHelpView([][]key.Binding{
 		{m.keymap.up, m.keymap.down},
-		{m.keymap.tab, m.keymap.enter, m.keymap.quit},
+		{m.keymap.tab, m.keymap.enter, m.keymap.previous, m.keymap.quit},
 	})
 
 	var views []string
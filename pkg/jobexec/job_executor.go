@@ -2,7 +2,12 @@ package jobexec
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/corentindeboisset/tera/pkg/cfg"
 	"github.com/corentindeboisset/tera/pkg/cmdrunr"
@@ -15,12 +20,16 @@ const (
 	STATE_RUNNING
 	STATE_SUCCESSFUL
 	STATE_FAILED
+	STATE_SKIPPED
+	STATE_CACHED
 )
 
 type CmdStatus struct {
 	state TaskState
 
 	Output cmdrunr.SafeBuffer
+	// Output of the previous run, in watch mode
+	PreviousOutput cmdrunr.SafeBuffer
 }
 
 type TaskStatus struct {
@@ -30,7 +39,16 @@ type TaskStatus struct {
 	AfterHooksSuccess  bool
 	MainTaskStatus     bool
 
+	// The task failed, but it has continue_on_error so the step goes on
+	ErrorIgnored bool
+
+	// Current attempt of the command, starting at 1, out of the attempts allowed by the retries
+	Attempt     int
+	MaxAttempts int
+
 	Output cmdrunr.SafeBuffer
+	// Output of the previous run, in watch mode
+	PreviousOutput cmdrunr.SafeBuffer
 }
 
 type StepStatus struct {
@@ -42,8 +60,50 @@ type StepStatus struct {
 	Mtx   sync.Mutex
 }
 
-func executeJob(ctx context.Context, basePath string, config *cfg.JobConfig, stepStatuses []StepStatus, readyToDisplay, done chan struct{}) {
+type JobStatus struct {
+	Steps      []StepStatus
+	AfterHooks *CmdStatus
+
+	// Size of the panel in which the outputs are displayed, given to the commands
+	OutputSize *cmdrunr.TerminalSize
+
+	result string
+	Mtx    sync.Mutex
+}
+
+// Returns the result of the job (one of the JOB_RESULT_* values), or an empty string if it is not finished
+func (j *JobStatus) Result() string {
+	j.Mtx.Lock()
+	defer j.Mtx.Unlock()
+
+	return j.result
+}
+
+// Size given to the commands when the outputs are not displayed in the interface
+const (
+	DEFAULT_OUTPUT_WIDTH  = 120
+	DEFAULT_OUTPUT_HEIGHT = 24
+)
+
+// Values of the TERA_JOB_STATUS variable, given to the run_after hooks of the job
+const (
+	JOB_RESULT_SUCCESS   = "success"
+	JOB_RESULT_FAILURE   = "failure"
+	JOB_RESULT_CANCELLED = "cancelled"
+)
+
+// Duration after which a run_after hook of the job is stopped, since it is not stopped by the cancellation of the job
+const AFTER_HOOK_TIMEOUT = 2 * time.Minute
+
+func executeJob(ctx context.Context, basePath string, globalEnv cfg.EnvConfig, config *cfg.JobConfig, params map[string]string, cache *taskCache, jobStatus *JobStatus, readyToDisplay, done chan struct{}) {
 	// First, initialize the status structs
+	if jobStatus.OutputSize == nil {
+		jobStatus.OutputSize = cmdrunr.NewTerminalSize(DEFAULT_OUTPUT_WIDTH, DEFAULT_OUTPUT_HEIGHT)
+	}
+	stepStatuses := jobStatus.Steps
+	if len(config.RunAfter) > 0 {
+		jobStatus.AfterHooks = &CmdStatus{}
+	}
 	for stepIdx, step := range config.Steps {
 		if len(step.RunBefore) > 0 {
 			stepStatuses[stepIdx].BeforeHooks = &CmdStatus{}
@@ -58,93 +118,288 @@ func executeJob(ctx context.Context, basePath string, config *cfg.JobConfig, ste
 	close(readyToDisplay)
 	defer close(done)
 
-	for stepIdx, step := range config.Steps {
+	runJob(ctx, basePath, globalEnv, config, params, cache, jobStatus, nil)
+}
+
+// Runs the steps of the job, then its run_after hooks, and sets its result.
+// If toRun is not nil, only the steps it marks are run, the other ones must be successful already.
+func runJob(ctx context.Context, basePath string, globalEnv cfg.EnvConfig, config *cfg.JobConfig, params map[string]string, cache *taskCache, jobStatus *JobStatus, toRun []bool) {
+	jobEnv := []cfg.EnvConfig{globalEnv, config.FileEnv, config.EnvConfig, paramsEnv(config, params)}
+	succeeded := runSteps(ctx, basePath, jobEnv, config, cache, jobStatus.Steps, jobStatus.OutputSize, toRun)
+
+	result := JOB_RESULT_SUCCESS
+	if ctx.Err() != nil {
+		result = JOB_RESULT_CANCELLED
+	} else if !succeeded {
+		result = JOB_RESULT_FAILURE
+	}
+
+	hooksOk := runJobAfterHooks(ctx, basePath, jobEnv, config, jobStatus, result)
+	if !hooksOk && result == JOB_RESULT_SUCCESS {
+		result = JOB_RESULT_FAILURE
+	}
+
+	jobStatus.Mtx.Lock()
+	jobStatus.result = result
+	jobStatus.Mtx.Unlock()
+}
+
+// Runs the run_after hooks of the job, like a "finally" block: they are run whatever the result of the steps.
+// The hooks are not interrupted when the job is cancelled, but each of them is stopped and fails after AFTER_HOOK_TIMEOUT.
+// They are all run even if one of them fails.
+func runJobAfterHooks(ctx context.Context, basePath string, jobEnv []cfg.EnvConfig, config *cfg.JobConfig, jobStatus *JobStatus, result string) bool {
+	if jobStatus.AfterHooks == nil {
+		return true
+	}
+
+	jobStatus.Mtx.Lock()
+	jobStatus.AfterHooks.state = STATE_RUNNING
+	jobStatus.Mtx.Unlock()
 
-		stepStatuses[stepIdx].Mtx.Lock()
-		stepStatuses[stepIdx].state = STATE_RUNNING
-		if stepStatuses[stepIdx].BeforeHooks != nil {
-			stepStatuses[stepIdx].BeforeHooks.state = STATE_RUNNING
-		}
-		stepStatuses[stepIdx].Mtx.Unlock()
-
-		for _, hook := range step.RunBefore {
-			// FIXME: the width/height values are not right
-			if !cmdrunr.RunCommand(ctx, basePath, hook.Path, hook.Cmd, &stepStatuses[stepIdx].BeforeHooks.Output, 120, 24) {
-				stepStatuses[stepIdx].Mtx.Lock()
-				stepStatuses[stepIdx].BeforeHooks.state = STATE_FAILED
-				stepStatuses[stepIdx].state = STATE_FAILED
-				stepStatuses[stepIdx].Mtx.Unlock()
-				return
+	env := append(slices.Clone(jobEnv), cfg.EnvConfig{Env: map[string]string{
+		"TERA_JOB_NAME":   config.Name,
+		"TERA_JOB_STATUS": result,
+	}})
+
+	hooksOk := true
+	for _, hook := range config.RunAfter {
+		hookCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), AFTER_HOOK_TIMEOUT)
+		if !cmdrunr.RunCommandInTerminal(hookCtx, basePath, hook, env, &jobStatus.AfterHooks.Output, jobStatus.OutputSize) {
+			if hookCtx.Err() != nil {
+				_, _ = fmt.Fprintf(&jobStatus.AfterHooks.Output, "\nThe hook was stopped after running for %s\n", AFTER_HOOK_TIMEOUT)
 			}
+			hooksOk = false
+		}
+		cancel()
+	}
+
+	jobStatus.Mtx.Lock()
+	if hooksOk {
+		jobStatus.AfterHooks.state = STATE_SUCCESSFUL
+	} else {
+		jobStatus.AfterHooks.state = STATE_FAILED
+	}
+	jobStatus.Mtx.Unlock()
+
+	return hooksOk
+}
+
+// Runs the steps following their needs, and returns whether they were all successful.
+// Once a step failed, no new step is started, unless the job has keep_going.
+// If toRun is not nil, the steps it does not mark are not run again, they kept the result of the previous run.
+func runSteps(ctx context.Context, basePath string, jobEnv []cfg.EnvConfig, config *cfg.JobConfig, cache *taskCache, stepStatuses []StepStatus, outputSize *cmdrunr.TerminalSize, toRun []bool) bool {
+	var stepFailed atomic.Bool
+	results := runGraph(config.StepNeeds(), func(stepIdx int) bool {
+		if toRun != nil && !toRun[stepIdx] {
+			return true
+		}
+		if ctx.Err() != nil || (stepFailed.Load() && !config.KeepGoing) {
+			return false
 		}
 
-		stepStatuses[stepIdx].Mtx.Lock()
-		if stepStatuses[stepIdx].BeforeHooks != nil {
-			stepStatuses[stepIdx].BeforeHooks.state = STATE_SUCCESSFUL
+		step := config.Steps[stepIdx]
+		stepEnv := append(slices.Clone(jobEnv), step.EnvConfig)
+
+		// A skipped step counts as a success for the steps that need it
+		if run, reason := evaluateConditions(ctx, basePath, stepEnv, config.Params, step.ConditionsConfig); !run {
+			skipStep(&stepStatuses[stepIdx], reason)
+			return true
 		}
-		stepStatuses[stepIdx].Mtx.Unlock()
 
-		// Within each step, the tasks are run asynchronously
-		var taskWg sync.WaitGroup
-		for taskIdx, task := range step.Tasks {
-			taskWg.Go(func() {
-				runTask(ctx, basePath, task, &stepStatuses[stepIdx].Tasks[taskIdx], &stepStatuses[stepIdx])
-			})
+		if !runStep(ctx, basePath, stepEnv, config.Params, cache, step, &stepStatuses[stepIdx], outputSize) {
+			stepFailed.Store(true)
+			return false
 		}
-		taskWg.Wait()
+		return true
+	})
 
-		// Read the status from the tasks
-		stepStatuses[stepIdx].Mtx.Lock()
-		tasksOk := true
-		for taskIdx := range stepStatuses[stepIdx].Tasks {
-			js := &stepStatuses[stepIdx].Tasks[taskIdx]
-			if js.state == STATE_FAILED {
-				tasksOk = false
+	return !slices.Contains(results, false)
+}
+
+// Runs the nodes of a dependency graph with as much parallelism as possible: each node starts as soon as
+// all the nodes it needs are successful. The nodes that need a failed node are not run.
+// Returns whether each node was run successfully.
+func runGraph(needs [][]int, run func(idx int) bool) []bool {
+	results := make([]bool, len(needs))
+	done := make([]chan struct{}, len(needs))
+	for idx := range needs {
+		done[idx] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	for idx := range needs {
+		wg.Go(func() {
+			defer close(done[idx])
+
+			for _, need := range needs[idx] {
+				<-done[need]
+				if !results[need] {
+					return
+				}
 			}
+
+			results[idx] = run(idx)
+		})
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Marks the step and all its tasks as skipped, and writes the reason in the output of the tasks
+func skipStep(stepStatus *StepStatus, reason string) {
+	stepStatus.Mtx.Lock()
+	defer stepStatus.Mtx.Unlock()
+
+	stepStatus.state = STATE_SKIPPED
+	for taskIdx := range stepStatus.Tasks {
+		_, _ = fmt.Fprintf(&stepStatus.Tasks[taskIdx].Output, "The step is skipped: %s\n", reason)
+		stepStatus.Tasks[taskIdx].state = STATE_SKIPPED
+	}
+}
+
+// Cause of the cancellation of the tasks of a step with fail_fast
+var errFailFast = errors.New("another task of the step failed")
+
+// Runs the hooks and the tasks of a step, and returns whether they were all successful
+func runStep(ctx context.Context, basePath string, stepEnv []cfg.EnvConfig, params []cfg.ParamConfig, cache *taskCache, step cfg.StepConfig, stepStatus *StepStatus, outputSize *cmdrunr.TerminalSize) bool {
+	stepStatus.Mtx.Lock()
+	stepStatus.state = STATE_RUNNING
+	if stepStatus.BeforeHooks != nil {
+		stepStatus.BeforeHooks.state = STATE_RUNNING
+	}
+	stepStatus.Mtx.Unlock()
+
+	for _, hook := range step.RunBefore {
+		if !cmdrunr.RunCommandInTerminal(ctx, basePath, hook, stepEnv, &stepStatus.BeforeHooks.Output, outputSize) {
+			stepStatus.Mtx.Lock()
+			stepStatus.BeforeHooks.state = STATE_FAILED
+			stepStatus.state = STATE_FAILED
+			stepStatus.Mtx.Unlock()
+			return false
+		}
+	}
+
+	stepStatus.Mtx.Lock()
+	if stepStatus.BeforeHooks != nil {
+		stepStatus.BeforeHooks.state = STATE_SUCCESSFUL
+	}
+	stepStatus.Mtx.Unlock()
+
+	// Within each step, the tasks are run in parallel, unless they need each other.
+	// With fail_fast, the first failure cancels the other tasks of the step.
+	tasksCtx, cancelTasks := context.WithCancelCause(ctx)
+	defer cancelTasks(nil)
+
+	results := runGraph(step.TaskNeeds(), func(taskIdx int) bool {
+		if tasksCtx.Err() != nil {
+			return false
 		}
-		if !tasksOk {
-			stepStatuses[stepIdx].state = STATE_FAILED
-			stepStatuses[stepIdx].Mtx.Unlock()
-			return
+
+		task := step.Tasks[taskIdx]
+		taskStatus := &stepStatus.Tasks[taskIdx]
+
+		taskEnv := append(slices.Clone(stepEnv), task.EnvConfig)
+		if run, reason := evaluateConditions(tasksCtx, basePath, taskEnv, params, task.ConditionsConfig); !run {
+			_, _ = fmt.Fprintf(&taskStatus.Output, "The task is skipped: %s\n", reason)
+			stepStatus.Mtx.Lock()
+			taskStatus.state = STATE_SKIPPED
+			stepStatus.Mtx.Unlock()
+			return true
 		}
-		if stepStatuses[stepIdx].AfterHooks != nil {
-			stepStatuses[stepIdx].AfterHooks.state = STATE_RUNNING
+
+		// The tasks whose sources did not change since their last success are not run again.
+		// The fingerprint is computed before the task runs, and stored once it succeeded.
+		fingerprint, fingerprintErr := cache.fingerprint(task, stepEnv)
+		if fingerprintErr == nil && cache.isUpToDate(step.Name, task, fingerprint) {
+			_, _ = fmt.Fprintf(&taskStatus.Output, "The task is up to date, its sources did not change since its last success\n")
+			stepStatus.Mtx.Lock()
+			taskStatus.state = STATE_CACHED
+			stepStatus.Mtx.Unlock()
+			return true
 		}
-		stepStatuses[stepIdx].Mtx.Unlock()
 
-		for _, hook := range step.RunAfter {
-			if !cmdrunr.RunCommand(ctx, basePath, hook.Path, hook.Cmd, &stepStatuses[stepIdx].AfterHooks.Output, 120, 24) {
-				stepStatuses[stepIdx].Mtx.Lock()
-				stepStatuses[stepIdx].AfterHooks.state = STATE_FAILED
-				stepStatuses[stepIdx].state = STATE_FAILED
-				stepStatuses[stepIdx].Mtx.Unlock()
-				return
+		success, stopped := runTask(tasksCtx, basePath, stepEnv, task, taskStatus, stepStatus, outputSize)
+		if success {
+			// The fingerprint of a task that may have been interrupted is not stored
+			if fingerprintErr == nil && tasksCtx.Err() == nil {
+				fingerprintErr = cache.store(step.Name, task, fingerprint)
+			}
+			if fingerprintErr != nil {
+				_, _ = fmt.Fprintf(&taskStatus.Output, "The fingerprint of the task could not be saved: %s\n", fingerprintErr)
 			}
+			return true
 		}
 
-		stepStatuses[stepIdx].Mtx.Lock()
-		if stepStatuses[stepIdx].AfterHooks != nil {
-			stepStatuses[stepIdx].AfterHooks.state = STATE_SUCCESSFUL
+		// A task that failed by itself after the cancellation is reported as a failure
+		if stopped && ctx.Err() == nil && errors.Is(context.Cause(tasksCtx), errFailFast) {
+			_, _ = fmt.Fprintf(&taskStatus.Output, "\nThe task was cancelled because another task of the step failed\n")
+			return false
+		}
+		if task.ContinueOnError {
+			stepStatus.Mtx.Lock()
+			taskStatus.ErrorIgnored = true
+			stepStatus.Mtx.Unlock()
+			return true
+		}
+		if step.FailFast {
+			cancelTasks(errFailFast)
 		}
-		stepStatuses[stepIdx].state = STATE_SUCCESSFUL
-		stepStatuses[stepIdx].Mtx.Unlock()
+		return false
+	})
+
+	stepStatus.Mtx.Lock()
+	if slices.Contains(results, false) {
+		stepStatus.state = STATE_FAILED
+		stepStatus.Mtx.Unlock()
+		return false
 	}
+	if stepStatus.AfterHooks != nil {
+		stepStatus.AfterHooks.state = STATE_RUNNING
+	}
+	stepStatus.Mtx.Unlock()
+
+	for _, hook := range step.RunAfter {
+		if !cmdrunr.RunCommandInTerminal(ctx, basePath, hook, stepEnv, &stepStatus.AfterHooks.Output, outputSize) {
+			stepStatus.Mtx.Lock()
+			stepStatus.AfterHooks.state = STATE_FAILED
+			stepStatus.state = STATE_FAILED
+			stepStatus.Mtx.Unlock()
+			return false
+		}
+	}
+
+	stepStatus.Mtx.Lock()
+	if stepStatus.AfterHooks != nil {
+		stepStatus.AfterHooks.state = STATE_SUCCESSFUL
+	}
+	stepStatus.state = STATE_SUCCESSFUL
+	stepStatus.Mtx.Unlock()
+
+	return true
 }
 
-func runTask(ctx context.Context, basePath string, config cfg.TaskConfig, taskStatus *TaskStatus, globalStatus *StepStatus) bool {
+// Runs the task with its hooks, and returns whether it succeeded, and whether it was stopped by the cancellation of the context.
+// The hooks inherit the environment of the task.
+func runTask(ctx context.Context, basePath string, stepEnv []cfg.EnvConfig, config cfg.TaskConfig, taskStatus *TaskStatus, globalStatus *StepStatus, outputSize *cmdrunr.TerminalSize) (bool, bool) {
+	hookEnv := append(slices.Clone(stepEnv), config.EnvConfig)
+
 	globalStatus.Mtx.Lock()
 	taskStatus.BeforeHooksSuccess = false
 	taskStatus.MainTaskStatus = false
 	taskStatus.AfterHooksSuccess = false
+	taskStatus.ErrorIgnored = false
+	taskStatus.Attempt = 0
+	taskStatus.MaxAttempts = config.Retries + 1
 	taskStatus.state = STATE_RUNNING
 	globalStatus.Mtx.Unlock()
 
 	for _, hook := range config.RunBefore {
-		if !cmdrunr.RunCommand(ctx, basePath, hook.Path, hook.Cmd, &taskStatus.Output, 120, 24) {
+		if success, stopped := cmdrunr.RunCancellableCommand(ctx, basePath, hook, hookEnv, &taskStatus.Output, outputSize); !success || stopped {
 			globalStatus.Mtx.Lock()
 			taskStatus.state = STATE_FAILED
 			globalStatus.Mtx.Unlock()
-			return false
+			return false, stopped
 		}
 	}
 
@@ -152,12 +407,12 @@ func runTask(ctx context.Context, basePath string, config cfg.TaskConfig, taskSt
 	taskStatus.BeforeHooksSuccess = true
 	globalStatus.Mtx.Unlock()
 
-	// run config.Cmd
-	if !cmdrunr.RunCommand(ctx, basePath, config.Path, config.Cmd, &taskStatus.Output, 120, 24) {
+	// run config.Cmd, and run it again on failure if the task has retries
+	if success, stopped := runTaskCmd(ctx, basePath, stepEnv, config, taskStatus, globalStatus, outputSize); !success {
 		globalStatus.Mtx.Lock()
 		taskStatus.state = STATE_FAILED
 		globalStatus.Mtx.Unlock()
-		return false
+		return false, stopped
 	}
 
 	globalStatus.Mtx.Lock()
@@ -165,11 +420,11 @@ func runTask(ctx context.Context, basePath string, config cfg.TaskConfig, taskSt
 	globalStatus.Mtx.Unlock()
 
 	for _, hook := range config.RunAfter {
-		if !cmdrunr.RunCommand(ctx, basePath, hook.Path, hook.Cmd, &taskStatus.Output, 120, 24) {
+		if success, stopped := cmdrunr.RunCancellableCommand(ctx, basePath, hook, hookEnv, &taskStatus.Output, outputSize); !success || stopped {
 			globalStatus.Mtx.Lock()
 			taskStatus.state = STATE_FAILED
 			globalStatus.Mtx.Unlock()
-			return false
+			return false, stopped
 		}
 	}
 
@@ -178,5 +433,33 @@ func runTask(ctx context.Context, basePath string, config cfg.TaskConfig, taskSt
 	taskStatus.AfterHooksSuccess = true
 	globalStatus.Mtx.Unlock()
 
-	return true
+	return true, false
+}
+
+// Runs the command of the task until it succeeds or there are no retries left, and returns whether it succeeded,
+// and whether it was stopped by the cancellation of the context
+func runTaskCmd(ctx context.Context, basePath string, stepEnv []cfg.EnvConfig, config cfg.TaskConfig, taskStatus *TaskStatus, globalStatus *StepStatus, outputSize *cmdrunr.TerminalSize) (bool, bool) {
+	for attempt := 1; attempt <= config.Retries+1; attempt++ {
+		globalStatus.Mtx.Lock()
+		taskStatus.Attempt = attempt
+		globalStatus.Mtx.Unlock()
+
+		// A command that exited successfully after being stopped did not complete, it is a failure
+		success, stopped := cmdrunr.RunCancellableCommand(ctx, basePath, config.CmdConfig, stepEnv, &taskStatus.Output, outputSize)
+		if success && !stopped && ctx.Err() == nil {
+			return true, false
+		}
+		if stopped || ctx.Err() != nil || attempt > config.Retries {
+			return false, stopped
+		}
+
+		_, _ = fmt.Fprintf(&taskStatus.Output, "\nThe attempt %d of %d failed, retrying in %s\n\n", attempt, config.Retries+1, config.RetryDelay)
+		select {
+		case <-ctx.Done():
+			return false, true
+		case <-time.After(config.RetryDelay):
+		}
+	}
+
+	return false, false
 }
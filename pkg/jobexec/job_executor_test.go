@@ -0,0 +1,382 @@
+package jobexec
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/corentindeboisset/tera/pkg/cfg"
+	"github.com/stretchr/testify/require"
+)
+
+// Parses the configuration, runs its first job with the default values of its parameters until the end, and returns its status
+func runTestJob(t *testing.T, ctx context.Context, configText string) *JobStatus {
+	t.Helper()
+
+	config, err := cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+
+	job := &config.Jobs[0]
+	params, _, err := resolveParams(job, nil)
+	require.Nil(t, err)
+
+	basePath := t.TempDir()
+	jobStatus := &JobStatus{Steps: make([]StepStatus, len(job.Steps))}
+	readyToDisplay := make(chan struct{})
+	done := make(chan struct{})
+
+	go executeJob(ctx, basePath, config.EnvConfig, job, params, loadTaskCache(basePath, job.Name, false), jobStatus, readyToDisplay, done)
+	<-readyToDisplay
+	<-done
+
+	return jobStatus
+}
+
+func TestJobAfterHooks(t *testing.T) {
+	t.Parallel()
+
+	configText := `
+jobs:
+  - name: failing job
+    steps:
+      - name: first step
+        tasks:
+          - name: failing task
+            cmd: exit 1
+      - name: second step
+        tasks:
+          - name: skipped task
+            cmd: echo never
+    run_after:
+      - cmd: echo "cleanup of $TERA_JOB_NAME after $TERA_JOB_STATUS"
+      - cmd: exit 3
+      - cmd: echo "second cleanup"
+`
+	jobStatus := runTestJob(t, context.Background(), configText)
+
+	require.Equal(t, STATE_FAILED, jobStatus.Steps[0].state)
+	require.Equal(t, STATE_NOT_STARTED, jobStatus.Steps[1].state)
+	require.Equal(t, STATE_FAILED, jobStatus.AfterHooks.state)
+
+	output := string(jobStatus.AfterHooks.Output.Bytes())
+	require.Contains(t, output, "cleanup of failing job after failure")
+	require.Contains(t, output, "second cleanup")
+
+	successConfig := `
+jobs:
+  - name: cancelled job
+    steps:
+      - name: first step
+        tasks:
+          - name: task
+            cmd: echo ok
+    run_after:
+      - cmd: echo "status=$TERA_JOB_STATUS"
+`
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	jobStatus = runTestJob(t, ctx, successConfig)
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.AfterHooks.state)
+	require.Contains(t, string(jobStatus.AfterHooks.Output.Bytes()), "status=cancelled")
+}
+
+func TestJobEnv(t *testing.T) {
+	t.Parallel()
+
+	configText := `
+env:
+  GLOBAL: global
+  OVERRIDDEN: global
+jobs:
+  - name: job with env
+    env:
+      JOB: job
+      OVERRIDDEN: job
+    steps:
+      - name: first step
+        env:
+          OVERRIDDEN: step
+        tasks:
+          - name: task
+            cmd: echo "$GLOBAL $JOB $OVERRIDDEN $TASK_ONLY"
+            env:
+              TASK_ONLY: task
+            run_after:
+              - cmd: echo "hook sees $TASK_ONLY and $HOOK_ONLY"
+                env:
+                  HOOK_ONLY: hook
+          - name: missing env file
+            cmd: echo never
+            env_file:
+              - missing.env
+`
+	jobStatus := runTestJob(t, context.Background(), configText)
+
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.Steps[0].Tasks[0].state)
+	output := string(jobStatus.Steps[0].Tasks[0].Output.Bytes())
+	require.Contains(t, output, "global job step task\n")
+	require.Contains(t, output, "hook sees task and hook\n")
+
+	require.Equal(t, STATE_FAILED, jobStatus.Steps[0].Tasks[1].state)
+	require.Contains(t, string(jobStatus.Steps[0].Tasks[1].Output.Bytes()), "The environment of the command could not be loaded")
+}
+
+func TestJobShellVariables(t *testing.T) {
+	t.Parallel()
+
+	// The variables of the shell are escaped in the configuration, so that they are expanded by the shell
+	configText := `
+vars:
+  GREETING: hello
+jobs:
+  - name: job with shell variables
+    params:
+      - name: mode
+        default: debug
+    env:
+      TASK_ONLY: ${GREETING}
+    steps:
+      - name: first step
+        tasks:
+          - name: task
+            cmd: for f in a b; do printf "$${f} "; done; echo "$${TASK_ONLY} $${TERA_PARAM_MODE} ${UNDECLARED:-fallback}"
+`
+	jobStatus := runTestJob(t, context.Background(), configText)
+
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.Steps[0].Tasks[0].state)
+	require.Contains(t, string(jobStatus.Steps[0].Tasks[0].Output.Bytes()), "a b hello debug fallback\n")
+}
+
+func TestJobNeeds(t *testing.T) {
+	t.Parallel()
+
+	configText := `
+jobs:
+  - name: pipeline
+    keep_going: true
+    steps:
+      - name: build
+        tasks:
+          - name: compile
+            cmd: sleep 0.2 && touch built
+          - name: package
+            cmd: test -f built && touch packaged
+            needs: [compile]
+      - name: lint
+        tasks:
+          - name: lint
+            cmd: test ! -f built
+      - name: broken
+        tasks:
+          - name: fail
+            cmd: exit 1
+      - name: integration
+        needs: [build, lint]
+        tasks:
+          - name: test
+            cmd: test -f packaged
+      - name: deploy
+        needs: [integration, broken]
+        tasks:
+          - name: deploy
+            cmd: echo deploy
+`
+	jobStatus := runTestJob(t, context.Background(), configText)
+
+	// The lint ran alongside the build, and the integration tests waited for both
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.Steps[0].state)
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.Steps[1].state)
+	require.Equal(t, STATE_FAILED, jobStatus.Steps[2].state)
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.Steps[3].state)
+
+	// The deploy needs the broken step, so it never started
+	require.Equal(t, STATE_NOT_STARTED, jobStatus.Steps[4].state)
+	require.Equal(t, JOB_RESULT_FAILURE, jobStatus.Result())
+}
+
+func TestJobFailurePolicies(t *testing.T) {
+	t.Parallel()
+
+	configText := `
+jobs:
+  - name: policies
+    steps:
+      - name: tolerant
+        tasks:
+          - name: flaky
+            cmd: exit 1
+            continue_on_error: true
+          - name: after flaky
+            cmd: echo ok
+            needs: [flaky]
+      - name: fail fast
+        needs: [tolerant]
+        fail_fast: true
+        tasks:
+          - name: broken
+            cmd: exit 2
+          - name: slow
+            cmd: sleep 10
+      - name: independent
+        needs: [tolerant]
+        tasks:
+          - name: slow
+            cmd: sleep 0.5
+      - name: last
+        needs: [independent]
+        tasks:
+          - name: never
+            cmd: echo never
+`
+	jobStatus := runTestJob(t, context.Background(), configText)
+
+	// The failure of the flaky task is ignored
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.Steps[0].state)
+	require.Equal(t, STATE_FAILED, jobStatus.Steps[0].Tasks[0].state)
+	require.True(t, jobStatus.Steps[0].Tasks[0].ErrorIgnored)
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.Steps[0].Tasks[1].state)
+
+	// The slow task was cancelled as soon as its sibling failed
+	require.Equal(t, STATE_FAILED, jobStatus.Steps[1].state)
+	require.Equal(t, STATE_FAILED, jobStatus.Steps[1].Tasks[1].state)
+	require.Contains(t, string(jobStatus.Steps[1].Tasks[1].Output.Bytes()), "cancelled because another task of the step failed")
+	require.NotContains(t, string(jobStatus.Steps[1].Tasks[0].Output.Bytes()), "cancelled")
+
+	// The step that was already running finished, but no new step was started after the failure
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.Steps[2].state)
+	require.Equal(t, STATE_NOT_STARTED, jobStatus.Steps[3].state)
+
+	require.Equal(t, JOB_RESULT_FAILURE, jobStatus.Result())
+	config, err := cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+	require.Equal(t, []string{"fail fast › broken", "fail fast › slow"}, listFailures(&config.Jobs[0], jobStatus))
+
+	keepGoingConfig := `
+jobs:
+  - name: keep going
+    keep_going: true
+    steps:
+      - name: broken
+        tasks:
+          - name: fail
+            cmd: exit 1
+      - name: independent
+        tasks:
+          - name: slow
+            cmd: sleep 0.2
+      - name: after independent
+        needs: [independent]
+        tasks:
+          - name: run
+            cmd: echo run
+      - name: after broken
+        needs: [broken]
+        tasks:
+          - name: skipped
+            cmd: echo never
+`
+	jobStatus = runTestJob(t, context.Background(), keepGoingConfig)
+
+	require.Equal(t, STATE_FAILED, jobStatus.Steps[0].state)
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.Steps[1].state)
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.Steps[2].state)
+	require.Equal(t, STATE_NOT_STARTED, jobStatus.Steps[3].state)
+	require.Equal(t, JOB_RESULT_FAILURE, jobStatus.Result())
+}
+
+func TestTaskRetries(t *testing.T) {
+	t.Parallel()
+
+	configText := `
+jobs:
+  - name: retries
+    steps:
+      - name: flaky
+        tasks:
+          - name: succeeds on the third attempt
+            cmd: echo x >> attempts && test $(wc -l < attempts) -ge 3
+            retries: 3
+            retry_delay: 50ms
+          - name: always times out
+            cmd: sleep 10
+            timeout: 100ms
+            retries: 1
+`
+	jobStatus := runTestJob(t, context.Background(), configText)
+
+	flakyTask := &jobStatus.Steps[0].Tasks[0]
+	require.Equal(t, STATE_SUCCESSFUL, flakyTask.state)
+	require.Equal(t, 3, flakyTask.Attempt)
+	require.Equal(t, 4, flakyTask.MaxAttempts)
+	require.Contains(t, string(flakyTask.Output.Bytes()), "The attempt 2 of 4 failed, retrying in 50ms")
+
+	hungTask := &jobStatus.Steps[0].Tasks[1]
+	require.Equal(t, STATE_FAILED, hungTask.state)
+	require.Equal(t, 2, hungTask.Attempt)
+	require.Equal(t, 2, strings.Count(string(hungTask.Output.Bytes()), "The command timed out after 100ms"))
+}
+
+func TestConditions(t *testing.T) {
+	t.Parallel()
+
+	configText := `
+jobs:
+  - name: conditions
+    params:
+      - name: deploy
+        type: bool
+        default: "no"
+    env:
+      TARGET: staging
+    steps:
+      - name: prepare
+        tasks:
+          - name: create marker
+            cmd: touch marker
+          - name: only in production
+            cmd: echo production
+            when:
+              env: TARGET=production
+          - name: skipped when the target is set
+            cmd: echo never
+            skip_if:
+              env: TARGET
+      - name: deploy
+        when:
+          params:
+            deploy: "yes"
+        tasks:
+          - name: deploy
+            cmd: echo deploying
+      - name: after deploy
+        needs: [prepare, deploy]
+        tasks:
+          - name: marker exists
+            cmd: echo marker
+            when:
+              file: marker
+              cmd: test -f marker
+          - name: marker is missing
+            cmd: echo never
+            when:
+              file: missing
+`
+	jobStatus := runTestJob(t, context.Background(), configText)
+
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.Steps[0].state)
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.Steps[0].Tasks[0].state)
+	require.Equal(t, STATE_SKIPPED, jobStatus.Steps[0].Tasks[1].state)
+	require.Contains(t, string(jobStatus.Steps[0].Tasks[1].Output.Bytes()), "the when condition does not hold: the variable TARGET is not \"production\"")
+	require.Equal(t, STATE_SKIPPED, jobStatus.Steps[0].Tasks[2].state)
+	require.Contains(t, string(jobStatus.Steps[0].Tasks[2].Output.Bytes()), "the skip_if condition holds")
+
+	// The whole step is skipped, and the steps that need it are still run
+	require.Equal(t, STATE_SKIPPED, jobStatus.Steps[1].state)
+	require.Equal(t, STATE_SKIPPED, jobStatus.Steps[1].Tasks[0].state)
+	require.Contains(t, string(jobStatus.Steps[1].Tasks[0].Output.Bytes()), "the parameter deploy is not \"yes\"")
+
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.Steps[2].state)
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.Steps[2].Tasks[0].state)
+	require.Equal(t, STATE_SKIPPED, jobStatus.Steps[2].Tasks[1].state)
+	require.Equal(t, JOB_RESULT_SUCCESS, jobStatus.Result())
+}
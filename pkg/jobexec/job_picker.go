@@ -0,0 +1,249 @@
+package jobexec
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/corentindeboisset/tera/pkg/cfg"
+	"github.com/corentindeboisset/tera/pkg/listviewport"
+)
+
+var (
+	pickerItemStyle        = lipgloss.NewStyle().PaddingLeft(2).MarginBottom(1)
+	pickerFocusedItemStyle = lipgloss.NewStyle().
+				Border(lipgloss.ThickBorder(), false, false, false, true).
+				BorderForeground(lipgloss.Color("111")).
+				PaddingLeft(1).
+				MarginBottom(1)
+	pickerNameStyle        = lipgloss.NewStyle().Bold(true)
+	pickerFocusedNameStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("111"))
+	pickerDetailStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("7"))
+)
+
+// Item of the job picker, showing the name, the description and the steps of a job
+type jobPickerItem struct {
+	job     *cfg.JobConfig
+	width   int
+	focused bool
+}
+
+func (i *jobPickerItem) Id() string {
+	return i.job.Name
+}
+
+func (i *jobPickerItem) Height() int {
+	return lipgloss.Height(i.View())
+}
+
+func (i *jobPickerItem) Focusable() bool {
+	return true
+}
+
+func (i *jobPickerItem) Resize(width int) {
+	i.width = width
+}
+
+func (i *jobPickerItem) View() string {
+	itemStyle, nameStyle := pickerItemStyle, pickerNameStyle
+	if i.focused {
+		itemStyle, nameStyle = pickerFocusedItemStyle, pickerFocusedNameStyle
+	}
+	detailStyle := pickerDetailStyle.MaxWidth(max(i.width-itemStyle.GetHorizontalFrameSize(), 0))
+
+	lines := []string{nameStyle.Render(i.job.Name)}
+	if len(i.job.Description) > 0 {
+		lines = append(lines, detailStyle.Render(i.job.Description))
+	}
+
+	stepNames := make([]string, len(i.job.Steps))
+	for stepIdx, step := range i.job.Steps {
+		stepNames[stepIdx] = step.Name
+	}
+	lines = append(lines, detailStyle.Render("Steps: "+strings.Join(stepNames, " › ")))
+
+	return itemStyle.Render(strings.Join(lines, "\n"))
+}
+
+// Interface listing the jobs, filtered by what the user types, to select the one to run
+type jobPickerModel struct {
+	width  int
+	height int
+
+	jobs     []cfg.JobConfig
+	filter   textinput.Model
+	list     listviewport.Model
+	items    []*jobPickerItem
+	selected int
+
+	picked    *cfg.JobConfig
+	cancelled bool
+}
+
+func newJobPickerModel(jobs []cfg.JobConfig) jobPickerModel {
+	m := jobPickerModel{
+		jobs:   jobs,
+		filter: textinput.New(),
+		list:   listviewport.New(30, 10, lipgloss.NewStyle().PaddingTop(1)),
+	}
+	m.filter.Prompt = "Filter: "
+	m.filter.Placeholder = "type to search a job"
+	m.filter.Focus()
+	m.refreshItems()
+
+	return m
+}
+
+// Fuzzy match of the pattern in the text: all the characters of the pattern must appear in order.
+// The score is higher when the matched characters are consecutive or at the start of words.
+func fuzzyScore(pattern, text string) (int, bool) {
+	pattern = strings.ToLower(pattern)
+	textRunes := []rune(strings.ToLower(text))
+
+	score := 0
+	textIdx := 0
+	previousMatch := -2
+	for _, patternRune := range pattern {
+		found := false
+		for ; textIdx < len(textRunes); textIdx++ {
+			if textRunes[textIdx] != patternRune {
+				continue
+			}
+
+			score++
+			if textIdx == previousMatch+1 {
+				score += 3
+			}
+			if textIdx == 0 || !unicode.IsLetter(textRunes[textIdx-1]) && !unicode.IsDigit(textRunes[textIdx-1]) {
+				score += 2
+			}
+			previousMatch = textIdx
+			textIdx++
+			found = true
+			break
+		}
+		if !found {
+			return 0, false
+		}
+	}
+
+	return score, true
+}
+
+// Filters the jobs with the content of the filter, and sorts them by relevance
+func (m *jobPickerModel) refreshItems() {
+	type scoredItem struct {
+		item  *jobPickerItem
+		score int
+	}
+
+	scoredItems := make([]scoredItem, 0, len(m.jobs))
+	for jobIdx := range m.jobs {
+		if score, ok := fuzzyScore(m.filter.Value(), m.jobs[jobIdx].Name); ok {
+			scoredItems = append(scoredItems, scoredItem{item: &jobPickerItem{job: &m.jobs[jobIdx], width: m.width}, score: score})
+		}
+	}
+	slices.SortStableFunc(scoredItems, func(a, b scoredItem) int {
+		return b.score - a.score
+	})
+
+	m.items = make([]*jobPickerItem, len(scoredItems))
+	listItems := make([]listviewport.ListItem, len(scoredItems))
+	for itemIdx, scored := range scoredItems {
+		m.items[itemIdx] = scored.item
+		listItems[itemIdx] = scored.item
+	}
+	m.list.SetItems(listItems)
+	m.list.Resize(m.width, max(m.height-2, 0))
+	m.selectItem(0)
+}
+
+func (m *jobPickerModel) selectItem(idx int) {
+	if len(m.items) == 0 {
+		m.selected = -1
+		return
+	}
+
+	if m.selected >= 0 && m.selected < len(m.items) {
+		m.items[m.selected].focused = false
+	}
+	m.selected = max(min(idx, len(m.items)-1), 0)
+	m.items[m.selected].focused = true
+	m.list.Focus(m.selected)
+}
+
+func (m jobPickerModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m jobPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.list.Resize(m.width, max(m.height-2, 0))
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			m.cancelled = true
+			return m, tea.Quit
+
+		case "enter":
+			if m.selected >= 0 {
+				m.picked = m.items[m.selected].job
+				return m, tea.Quit
+			}
+			return m, nil
+
+		case "up", "shift+tab":
+			m.selectItem(m.selected - 1)
+			return m, nil
+
+		case "down", "tab":
+			m.selectItem(m.selected + 1)
+			return m, nil
+		}
+	}
+
+	previousFilter := m.filter.Value()
+	var cmd tea.Cmd
+	m.filter, cmd = m.filter.Update(msg)
+	if m.filter.Value() != previousFilter {
+		m.refreshItems()
+	}
+
+	return m, cmd
+}
+
+func (m jobPickerModel) View() string {
+	if m.picked != nil || m.cancelled {
+		return ""
+	}
+
+	listView := pickerDetailStyle.PaddingTop(1).Render("No job matches the filter")
+	if len(m.items) > 0 {
+		listView = m.list.View()
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, m.filter.View(), listView)
+}
+
+// Opens the job picker, and returns the job selected by the user, or ErrJobCancelled if they gave up
+func pickJob(jobs []cfg.JobConfig) (*cfg.JobConfig, error) {
+	finalModel, err := tea.NewProgram(newJobPickerModel(jobs), tea.WithAltScreen()).Run()
+	if err != nil {
+		return nil, err
+	}
+
+	pickerModel := finalModel.(jobPickerModel)
+	if pickerModel.cancelled || pickerModel.picked == nil {
+		return nil, ErrJobCancelled
+	}
+
+	return pickerModel.picked, nil
+}
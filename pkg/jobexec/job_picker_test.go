@@ -0,0 +1,74 @@
+package jobexec
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/corentindeboisset/tera/pkg/cfg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	t.Parallel()
+
+	_, ok := fuzzyScore("dpl", "deploy")
+	require.True(t, ok)
+	_, ok = fuzzyScore("lpd", "deploy")
+	require.False(t, ok)
+
+	// Consecutive characters and word starts score higher
+	prefixScore, _ := fuzzyScore("dep", "deploy")
+	scatteredScore, _ := fuzzyScore("dep", "dead pipe")
+	require.Greater(t, prefixScore, scatteredScore)
+}
+
+func TestJobPicker(t *testing.T) {
+	t.Parallel()
+
+	configText := `
+jobs:
+  - name: build
+    description: Build the project
+    steps:
+      - name: compile
+        tasks:
+          - name: go
+            cmd: go build ./...
+  - name: deploy-staging
+    steps:
+      - name: deploy
+        tasks:
+          - name: deploy
+            cmd: echo deploy
+  - name: test
+    steps:
+      - name: unit
+        tasks:
+          - name: go
+            cmd: go test ./...
+`
+	config, err := cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+
+	var model tea.Model = newJobPickerModel(config.Jobs)
+	model, _ = model.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+
+	view := model.View()
+	require.Contains(t, view, "Build the project")
+	require.Contains(t, view, "Steps: compile")
+
+	// Filter the jobs, then pick the first match
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("stg")})
+	require.Len(t, model.(jobPickerModel).items, 1)
+
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	require.NotNil(t, cmd)
+	require.Equal(t, "deploy-staging", model.(jobPickerModel).picked.Name)
+
+	// Without a match, nothing is picked
+	model = newJobPickerModel(config.Jobs)
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("xyz")})
+	require.Contains(t, model.View(), "No job matches the filter")
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	require.Nil(t, model.(jobPickerModel).picked)
+}
@@ -0,0 +1,202 @@
+package jobexec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/corentindeboisset/tera/pkg/cfg"
+	"github.com/corentindeboisset/tera/pkg/cmdrunr"
+)
+
+// An output of the job that is printed line by line, each line being prefixed by the name of its task
+type outputStream struct {
+	prefix  string
+	buffer  *cmdrunr.SafeBuffer
+	offset  int
+	pending []byte
+}
+
+// Prints the complete lines written since the last call. If final is true, the incomplete last line is printed too
+func (o *outputStream) flush(w io.Writer, final bool) {
+	newContent := o.buffer.BytesFrom(o.offset)
+	o.offset += len(newContent)
+	o.pending = append(o.pending, newContent...)
+
+	for {
+		lineEnd := bytes.IndexByte(o.pending, '\n')
+		if lineEnd < 0 {
+			break
+		}
+		_, _ = fmt.Fprintf(w, "%s %s\n", o.prefix, bytes.TrimSuffix(o.pending[:lineEnd], []byte("\r")))
+		o.pending = o.pending[lineEnd+1:]
+	}
+
+	if final && len(o.pending) > 0 {
+		_, _ = fmt.Fprintf(w, "%s %s\n", o.prefix, o.pending)
+		o.pending = nil
+	}
+}
+
+func newOutputStreams(config *cfg.JobConfig, jobStatus *JobStatus) []*outputStream {
+	streams := make([]*outputStream, 0)
+	for stepIdx, step := range config.Steps {
+		stepStatus := &jobStatus.Steps[stepIdx]
+		if stepStatus.BeforeHooks != nil {
+			streams = append(streams, &outputStream{
+				prefix: fmt.Sprintf("[%s › run_before]", step.Name),
+				buffer: &stepStatus.BeforeHooks.Output,
+			})
+		}
+		for taskIdx, task := range step.Tasks {
+			streams = append(streams, &outputStream{
+				prefix: fmt.Sprintf("[%s › %s]", step.Name, task.Name),
+				buffer: &stepStatus.Tasks[taskIdx].Output,
+			})
+		}
+		if stepStatus.AfterHooks != nil {
+			streams = append(streams, &outputStream{
+				prefix: fmt.Sprintf("[%s › run_after]", step.Name),
+				buffer: &stepStatus.AfterHooks.Output,
+			})
+		}
+	}
+	if jobStatus.AfterHooks != nil {
+		streams = append(streams, &outputStream{
+			prefix: "[run_after]",
+			buffer: &jobStatus.AfterHooks.Output,
+		})
+	}
+
+	return streams
+}
+
+// Prints the outputs of the job on the standard output as they are written, without any interactive interface.
+// It returns once the job is done, after having printed a summary of the results.
+func streamJob(config *cfg.JobConfig, jobStatus *JobStatus, cancelJob context.CancelFunc, jobDone chan struct{}) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	defer signal.Stop(sigChan)
+
+	streams := newOutputStreams(config, jobStatus)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sigChan:
+			cancelJob()
+
+		case <-ticker.C:
+			for _, stream := range streams {
+				stream.flush(os.Stdout, false)
+			}
+
+		case <-jobDone:
+			for _, stream := range streams {
+				stream.flush(os.Stdout, true)
+			}
+			printSummary(os.Stdout, config, jobStatus)
+			return
+		}
+	}
+}
+
+func formatSummaryLine(state TaskState, name string) string {
+	switch state {
+	case STATE_SUCCESSFUL:
+		return fmt.Sprintf("%s %s", successFlag, name)
+	case STATE_FAILED:
+		return fmt.Sprintf("%s %s", failureFlag, name)
+	case STATE_RUNNING:
+		return fmt.Sprintf("… %s (interrupted)", name)
+	case STATE_SKIPPED:
+		return fmt.Sprintf("%s %s (skipped)", skippedFlag, name)
+	case STATE_CACHED:
+		return fmt.Sprintf("%s %s (up to date)", cachedFlag, name)
+	}
+
+	return fmt.Sprintf("- %s (not started)", name)
+}
+
+func printSummary(w io.Writer, config *cfg.JobConfig, jobStatus *JobStatus) {
+	_, _ = fmt.Fprintf(w, "\nSummary of the job \"%s\":\n", config.Name)
+
+	for stepIdx, step := range config.Steps {
+		stepStatus := &jobStatus.Steps[stepIdx]
+		stepStatus.Mtx.Lock()
+		_, _ = fmt.Fprintf(w, "  %s\n", formatSummaryLine(stepStatus.state, step.Name))
+		if stepStatus.BeforeHooks != nil {
+			_, _ = fmt.Fprintf(w, "      %s\n", formatSummaryLine(stepStatus.BeforeHooks.state, "Run-Before hooks"))
+		}
+		for taskIdx, task := range step.Tasks {
+			summaryLine := formatSummaryLine(stepStatus.Tasks[taskIdx].state, task.Name)
+			if stepStatus.Tasks[taskIdx].ErrorIgnored {
+				summaryLine += " (error ignored)"
+			}
+			_, _ = fmt.Fprintf(w, "      %s\n", summaryLine)
+		}
+		if stepStatus.AfterHooks != nil {
+			_, _ = fmt.Fprintf(w, "      %s\n", formatSummaryLine(stepStatus.AfterHooks.state, "Run-After hooks"))
+		}
+		stepStatus.Mtx.Unlock()
+	}
+
+	jobStatus.Mtx.Lock()
+	if jobStatus.AfterHooks != nil {
+		_, _ = fmt.Fprintf(w, "  %s\n", formatSummaryLine(jobStatus.AfterHooks.state, "Job Run-After hooks"))
+	}
+	result := jobStatus.result
+	jobStatus.Mtx.Unlock()
+
+	printFailures(w, config, jobStatus)
+	_, _ = fmt.Fprintf(w, "\nResult: %s\n", result)
+}
+
+// Returns the names of the parts of the job that failed, in the order of the configuration.
+// The tasks with continue_on_error are not reported.
+func listFailures(config *cfg.JobConfig, jobStatus *JobStatus) []string {
+	failures := make([]string, 0)
+	for stepIdx, step := range config.Steps {
+		stepStatus := &jobStatus.Steps[stepIdx]
+		stepStatus.Mtx.Lock()
+		if stepStatus.BeforeHooks != nil && stepStatus.BeforeHooks.state == STATE_FAILED {
+			failures = append(failures, fmt.Sprintf("%s › Run-Before hooks", step.Name))
+		}
+		for taskIdx, task := range step.Tasks {
+			if stepStatus.Tasks[taskIdx].state == STATE_FAILED && !stepStatus.Tasks[taskIdx].ErrorIgnored {
+				failures = append(failures, fmt.Sprintf("%s › %s", step.Name, task.Name))
+			}
+		}
+		if stepStatus.AfterHooks != nil && stepStatus.AfterHooks.state == STATE_FAILED {
+			failures = append(failures, fmt.Sprintf("%s › Run-After hooks", step.Name))
+		}
+		stepStatus.Mtx.Unlock()
+	}
+
+	jobStatus.Mtx.Lock()
+	if jobStatus.AfterHooks != nil && jobStatus.AfterHooks.state == STATE_FAILED {
+		failures = append(failures, "Job Run-After hooks")
+	}
+	jobStatus.Mtx.Unlock()
+
+	return failures
+}
+
+// Prints the list of everything that failed in the job, if anything did
+func printFailures(w io.Writer, config *cfg.JobConfig, jobStatus *JobStatus) {
+	failures := listFailures(config, jobStatus)
+	if len(failures) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "\nFailures:\n")
+	for _, failure := range failures {
+		_, _ = fmt.Fprintf(w, "  %s %s\n", failureFlag, failure)
+	}
+}
@@ -0,0 +1,28 @@
+package jobexec
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/corentindeboisset/tera/pkg/cmdrunr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputStream(t *testing.T) {
+	t.Parallel()
+
+	var buffer cmdrunr.SafeBuffer
+	var printed bytes.Buffer
+	stream := outputStream{prefix: "[step › task]", buffer: &buffer}
+
+	_, _ = buffer.Write([]byte("first line\r\nsecond "))
+	stream.flush(&printed, false)
+	require.Equal(t, "[step › task] first line\n", printed.String())
+
+	_, _ = buffer.Write([]byte("line\nlast"))
+	stream.flush(&printed, false)
+	require.Equal(t, "[step › task] first line\n[step › task] second line\n", printed.String())
+
+	stream.flush(&printed, true)
+	require.Equal(t, "[step › task] first line\n[step › task] second line\n[step › task] last\n", printed.String())
+}
@@ -0,0 +1,156 @@
+package jobexec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/corentindeboisset/tera/pkg/cfg"
+)
+
+var (
+	promptTitleStyle   = lipgloss.NewStyle().Bold(true)
+	promptNameStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("111"))
+	promptHelpStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("7"))
+	promptErrorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	selectedValueStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("111")).Underline(true)
+)
+
+// Interface that asks the values of the missing parameters one after the other.
+// The parameters with a list of accepted values are selected among them, the others are typed.
+type paramPromptModel struct {
+	jobName string
+	params  []cfg.ParamConfig
+	values  map[string]string
+
+	current   int
+	input     textinput.Model
+	choice    int
+	err       string
+	cancelled bool
+}
+
+func newParamPromptModel(jobName string, params []cfg.ParamConfig) paramPromptModel {
+	m := paramPromptModel{
+		jobName: jobName,
+		params:  params,
+		values:  make(map[string]string, len(params)),
+		input:   textinput.New(),
+	}
+	m.input.Prompt = "> "
+	m.input.Focus()
+
+	return m
+}
+
+func (m paramPromptModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m paramPromptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
+	}
+
+	param := m.params[m.current]
+	choices := param.Choices()
+
+	switch keyMsg.String() {
+	case "ctrl+c", "esc":
+		m.cancelled = true
+		return m, tea.Quit
+
+	case "enter":
+		value := m.input.Value()
+		if len(choices) > 0 {
+			value = choices[m.choice]
+		}
+
+		normalized, err := param.NormalizeValue(value)
+		if err != nil {
+			m.err = err.Error()
+			return m, nil
+		}
+
+		m.values[param.Name] = normalized
+		m.current++
+		m.choice = 0
+		m.err = ""
+		m.input.Reset()
+		if m.current >= len(m.params) {
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+
+	if len(choices) > 0 {
+		switch keyMsg.String() {
+		case "up", "left", "k", "shift+tab":
+			m.choice = (m.choice + len(choices) - 1) % len(choices)
+		case "down", "right", "j", "tab":
+			m.choice = (m.choice + 1) % len(choices)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m paramPromptModel) View() string {
+	if m.current >= len(m.params) || m.cancelled {
+		return ""
+	}
+
+	var view strings.Builder
+	view.WriteString(promptTitleStyle.Render(fmt.Sprintf("The job \"%s\" needs a value for the parameter:", m.jobName)))
+	view.WriteString("\n\n")
+
+	param := m.params[m.current]
+	view.WriteString(promptNameStyle.Render(param.Name))
+	if len(param.Description) > 0 {
+		view.WriteString(" - " + param.Description)
+	}
+	view.WriteString("\n")
+
+	if choices := param.Choices(); len(choices) > 0 {
+		for choiceIdx, choice := range choices {
+			if choiceIdx == m.choice {
+				view.WriteString("> " + selectedValueStyle.Render(choice) + "\n")
+			} else {
+				view.WriteString("  " + choice + "\n")
+			}
+		}
+	} else {
+		view.WriteString(m.input.View() + "\n")
+	}
+
+	if len(m.err) > 0 {
+		view.WriteString(promptErrorStyle.Render(m.err) + "\n")
+	}
+
+	view.WriteString("\n" + promptHelpStyle.Render("↵/Enter to confirm, Esc to cancel") + "\n")
+
+	return view.String()
+}
+
+// Asks the values of the missing parameters in the terminal, and returns ErrJobCancelled if the user gave up
+func promptParams(job *cfg.JobConfig, missing []cfg.ParamConfig) (map[string]string, error) {
+	finalModel, err := tea.NewProgram(newParamPromptModel(job.Name, missing)).Run()
+	if err != nil {
+		return nil, err
+	}
+
+	promptModel := finalModel.(paramPromptModel)
+	if promptModel.cancelled {
+		return nil, ErrJobCancelled
+	}
+
+	return promptModel.values, nil
+}
@@ -0,0 +1,116 @@
+package jobexec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/corentindeboisset/tera/pkg/cfg"
+)
+
+// Parses the "name=value" arguments given on the command line
+func ParseParamArgs(args []string) (map[string]string, error) {
+	params := make(map[string]string, len(args))
+	for _, arg := range args {
+		name, value, found := strings.Cut(arg, "=")
+		if !found || len(name) == 0 {
+			return nil, fmt.Errorf("the parameter \"%s\" must be given as name=value", arg)
+		}
+		params[name] = value
+	}
+
+	return params, nil
+}
+
+// Returns the job with the given name, or the job named "default" if no name is given
+func findJob(config *cfg.ConfigFile, jobToRun string) *cfg.JobConfig {
+	if len(jobToRun) == 0 {
+		jobToRun = "default"
+	}
+
+	for jobIdx := range config.Jobs {
+		if config.Jobs[jobIdx].Name == jobToRun {
+			return &config.Jobs[jobIdx]
+		}
+	}
+
+	return nil
+}
+
+// Checks the parameters given for the job and completes them with the default values.
+// It also returns the required parameters that were not given.
+func resolveParams(job *cfg.JobConfig, given map[string]string) (map[string]string, []cfg.ParamConfig, error) {
+	values := make(map[string]string, len(job.Params))
+	missing := make([]cfg.ParamConfig, 0)
+
+	for name := range given {
+		found := false
+		for _, param := range job.Params {
+			found = found || param.Name == name
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("the job \"%s\" has no parameter named \"%s\"", job.Name, name)
+		}
+	}
+
+	for _, param := range job.Params {
+		if value, ok := given[param.Name]; ok {
+			normalized, err := param.NormalizeValue(value)
+			if err != nil {
+				return nil, nil, err
+			}
+			values[param.Name] = normalized
+		} else if param.Default != nil {
+			// The default values have already been validated with the configuration
+			values[param.Name], _ = param.NormalizeValue(*param.Default)
+		} else {
+			missing = append(missing, param)
+		}
+	}
+
+	return values, missing, nil
+}
+
+// Returns the environment that exposes the parameters to the commands of the job
+func paramsEnv(job *cfg.JobConfig, values map[string]string) cfg.EnvConfig {
+	env := make(map[string]string, len(values))
+	for _, param := range job.Params {
+		if value, ok := values[param.Name]; ok {
+			env[param.EnvName()] = value
+		}
+	}
+
+	return cfg.EnvConfig{Env: env}
+}
+
+// Returns the completions of a --param flag for the given job: the names of the parameters,
+// then the accepted values once the name is complete.
+func GetParamCompletions(confPath, jobToRun, toComplete string) []string {
+	config, err := cfg.FindAndParseConfig(confPath)
+	if err != nil {
+		return nil
+	}
+
+	job := findJob(config, jobToRun)
+	if job == nil {
+		return nil
+	}
+
+	completions := make([]string, 0)
+	name, _, hasValue := strings.Cut(toComplete, "=")
+	for _, param := range job.Params {
+		if !hasValue {
+			completion := param.Name + "="
+			if len(param.Description) > 0 {
+				// The description is displayed by the shells that support it
+				completion += "\t" + param.Description
+			}
+			completions = append(completions, completion)
+		} else if param.Name == name {
+			for _, choice := range param.Choices() {
+				completions = append(completions, fmt.Sprintf("%s=%s", param.Name, choice))
+			}
+		}
+	}
+
+	return completions
+}
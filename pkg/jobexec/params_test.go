@@ -0,0 +1,85 @@
+package jobexec
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/corentindeboisset/tera/pkg/cfg"
+	"github.com/stretchr/testify/require"
+)
+
+const paramsConfig = `
+jobs:
+  - name: deploy
+    params:
+      - name: env
+        type: enum
+        values: [staging, production]
+      - name: dry_run
+        type: bool
+        default: "no"
+      - name: tag
+    steps:
+      - name: deploy
+        tasks:
+          - name: deploy
+            cmd: echo "$TERA_PARAM_TAG $TERA_PARAM_ENV $TERA_PARAM_DRY_RUN"
+`
+
+func TestResolveParams(t *testing.T) {
+	t.Parallel()
+
+	config, err := cfg.ParseConfig([]byte(paramsConfig))
+	require.Nil(t, err)
+	job := &config.Jobs[0]
+
+	params, err := ParseParamArgs([]string{"env=staging", "tag=v1=final"})
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"env": "staging", "tag": "v1=final"}, params)
+
+	_, err = ParseParamArgs([]string{"env"})
+	require.ErrorContains(t, err, "must be given as name=value")
+
+	values, missing, err := resolveParams(job, params)
+	require.Nil(t, err)
+	require.Empty(t, missing)
+	require.Equal(t, map[string]string{"env": "staging", "dry_run": "false", "tag": "v1=final"}, values)
+	require.Equal(t, map[string]string{
+		"TERA_PARAM_ENV":     "staging",
+		"TERA_PARAM_DRY_RUN": "false",
+		"TERA_PARAM_TAG":     "v1=final",
+	}, paramsEnv(job, values).Env)
+
+	_, missing, err = resolveParams(job, map[string]string{"dry_run": "true"})
+	require.Nil(t, err)
+	require.Len(t, missing, 2)
+	require.Equal(t, "env", missing[0].Name)
+	require.Equal(t, "tag", missing[1].Name)
+
+	_, _, err = resolveParams(job, map[string]string{"env": "dev"})
+	require.ErrorContains(t, err, "must be one of: staging, production")
+
+	_, _, err = resolveParams(job, map[string]string{"unknown": "1"})
+	require.ErrorContains(t, err, "the job \"deploy\" has no parameter named \"unknown\"")
+}
+
+func TestParamPrompt(t *testing.T) {
+	t.Parallel()
+
+	config, err := cfg.ParseConfig([]byte(paramsConfig))
+	require.Nil(t, err)
+	job := &config.Jobs[0]
+
+	var model tea.Model = newParamPromptModel(job.Name, []cfg.ParamConfig{job.Params[0], job.Params[2]})
+
+	// The enum value is selected in the list, then the tag is typed
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("v2")})
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	require.NotNil(t, cmd)
+	promptModel := model.(paramPromptModel)
+	require.False(t, promptModel.cancelled)
+	require.Equal(t, map[string]string{"env": "production", "tag": "v2"}, promptModel.values)
+}
@@ -2,15 +2,23 @@ package jobexec
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"os/signal"
 	"syscall"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
 	"github.com/corentindeboisset/tera/pkg/cfg"
 )
 
+var (
+	ErrJobFailed    = errors.New("the job failed")
+	ErrJobCancelled = errors.New("the job was cancelled")
+)
+
 func GetJobList(confPath string) []string {
 	config, err := cfg.FindAndParseConfig(confPath)
 	if err != nil {
@@ -25,7 +33,12 @@ func GetJobList(confPath string) []string {
 	return results
 }
 
-func ExecuteJob(confPath string, jobToRun string) error {
+// Runs the job with the given parameters, and returns ErrJobFailed or ErrJobCancelled if it did not succeed.
+// Without a terminal on the standard output, or if noTui is true, the outputs are streamed instead of displayed in an interface,
+// and the missing parameters are an error instead of being prompted for.
+// If force is true, the tasks are run even if they are up to date with their sources.
+// If watch is true, the job is restarted each time its watched files change, until the interface is closed.
+func ExecuteJob(confPath string, jobToRun string, params map[string]string, noTui, force, watch bool) error {
 	config, err := cfg.FindAndParseConfig(confPath)
 	if err != nil {
 		return err
@@ -33,31 +46,97 @@ func ExecuteJob(confPath string, jobToRun string) error {
 
 	cfg.SetupLogs(config.LogFilePath)
 
-	var pickedJob *cfg.JobConfig
-	for _, job := range config.Jobs {
-		if (len(jobToRun) > 0 && job.Name == jobToRun) || job.Name == "default" {
-			pickedJob = &job
-		}
+	useTui := !noTui && term.IsTerminal(os.Stdout.Fd())
+	if watch && !useTui {
+		return errWatchWithoutTui
 	}
 
+	pickedJob := findJob(config, jobToRun)
 	if pickedJob == nil {
 		if len(jobToRun) > 0 {
 			return fmt.Errorf("there is no job named \"%s\"", jobToRun)
 		}
-		return fmt.Errorf("there is no job named \"default\"")
+		if !useTui || !term.IsTerminal(os.Stdin.Fd()) || len(config.Jobs) == 0 {
+			return fmt.Errorf("there is no job named \"default\"")
+		}
+
+		// Without a default job, the user picks the job to run
+		pickedJob, err = pickJob(config.Jobs)
+		if err != nil {
+			return err
+		}
+	}
+
+	paramValues, missingParams, err := resolveParams(pickedJob, params)
+	if err != nil {
+		return err
+	}
+	if len(missingParams) > 0 {
+		if !useTui || !term.IsTerminal(os.Stdin.Fd()) {
+			return fmt.Errorf("the parameter \"%s\" of the job \"%s\" is required", missingParams[0].Name, pickedJob.Name)
+		}
+
+		promptedValues, err := promptParams(pickedJob, missingParams)
+		if err != nil {
+			return err
+		}
+		maps.Copy(paramValues, promptedValues)
 	}
 
-	stepStatuses := make([]StepStatus, len(pickedJob.Steps))
+	jobStatus := &JobStatus{Steps: make([]StepStatus, len(pickedJob.Steps))}
 
 	readyToDisplay := make(chan struct{})
 	jobDone := make(chan struct{})
 
 	ctx, cancelJob := context.WithCancel(context.Background())
+	defer cancelJob()
 
 	// Run the job in a goroutine. The synchronisation is handled by the channels
-	go executeJob(ctx, config.BasePath, pickedJob, stepStatuses, readyToDisplay, jobDone)
-	<-readyToDisplay
-	program := tea.NewProgram(newModel(pickedJob, stepStatuses), tea.WithAltScreen(), tea.WithMouseCellMotion(), tea.WithoutSignalHandler())
+	cache := loadTaskCache(config.BasePath, pickedJob.Name, force)
+	if !watch {
+		go executeJob(ctx, config.BasePath, config.EnvConfig, pickedJob, paramValues, cache, jobStatus, readyToDisplay, jobDone)
+		<-readyToDisplay
+	} else {
+		changedSteps, err := watchJobFiles(ctx, config.BasePath, pickedJob)
+		if err != nil {
+			return err
+		}
+
+		// Each run has its own context, to be cancelled when the files change. jobDone is closed after the last run.
+		runCtx, cancelRun := context.WithCancel(ctx)
+		firstRunDone := make(chan struct{})
+		go executeJob(runCtx, config.BasePath, config.EnvConfig, pickedJob, paramValues, cache, jobStatus, readyToDisplay, firstRunDone)
+		<-readyToDisplay
+
+		restart := func(runCtx context.Context, steps []int, done chan struct{}) {
+			restartJob(runCtx, config.BasePath, config.EnvConfig, pickedJob, paramValues, jobStatus, steps, done)
+		}
+		go rerunOnChanges(ctx, changedSteps, cancelRun, firstRunDone, restart, jobDone)
+	}
+
+	if !useTui {
+		streamJob(pickedJob, jobStatus, cancelJob, jobDone)
+	} else if err := displayJob(pickedJob, jobStatus, cancelJob, jobDone, watch); err != nil {
+		return err
+	} else if jobStatus.Result() == JOB_RESULT_FAILURE {
+		// The interface is closed, so the failures are reported on the terminal
+		printFailures(os.Stdout, pickedJob, jobStatus)
+	}
+
+	switch jobStatus.Result() {
+	case JOB_RESULT_FAILURE:
+		return ErrJobFailed
+	case JOB_RESULT_CANCELLED:
+		return ErrJobCancelled
+	}
+
+	return nil
+}
+
+// Displays the job in the interactive interface. Once the interface is closed, the job is cancelled
+// and the function returns when it is done.
+func displayJob(pickedJob *cfg.JobConfig, jobStatus *JobStatus, cancelJob context.CancelFunc, jobDone chan struct{}, watch bool) error {
+	program := tea.NewProgram(newModel(pickedJob, jobStatus, watch), tea.WithAltScreen(), tea.WithMouseCellMotion(), tea.WithoutSignalHandler())
 
 	programErr := make(chan error)
 	go func() {
@@ -72,6 +151,7 @@ func ExecuteJob(confPath string, jobToRun string) error {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
 	defer signal.Stop(sigChan)
 
+	var err error
 	select {
 	case <-sigChan:
 		program.Quit()
@@ -79,13 +159,22 @@ func ExecuteJob(confPath string, jobToRun string) error {
 		// nothing to do, the program already quit
 	}
 
-	// Run the cleanup, and wait for all tasks to be done
+	// Run the cleanup
 	cancelJob()
-	<-jobDone
 
 	// Ensure the program is finished before proceeding
 	for err = range programErr {
 	}
 
+	// Wait for all tasks to be done. The run_after hooks of the job are still executed after a cancellation
+	select {
+	case <-jobDone:
+	default:
+		if len(pickedJob.RunAfter) > 0 {
+			fmt.Printf("Waiting for the run_after hooks of the job to finish...\n")
+		}
+		<-jobDone
+	}
+
 	return err
 }
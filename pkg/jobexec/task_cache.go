@@ -0,0 +1,300 @@
+package jobexec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/corentindeboisset/tera/pkg/cfg"
+)
+
+const (
+	CACHE_DIR          = ".tera"
+	FINGERPRINTS_FILE  = "fingerprints.json"
+	CACHE_GITIGNORE    = ".gitignore"
+	FINGERPRINT_FORMAT = "v3"
+)
+
+// Store of the fingerprints of the tasks of a job that declare sources. A task whose fingerprint did not change
+// since its last successful run, and whose generated files still exist, is up to date and does not need to run.
+// The fingerprints are kept in the .tera directory next to the configuration file.
+type taskCache struct {
+	basePath string
+	jobName  string
+	force    bool
+
+	fingerprints map[string]string
+	mtx          sync.Mutex
+}
+
+// Loads the fingerprints stored for the job. If force is true, no task is ever up to date,
+// but the fingerprints are still updated after the tasks run.
+func loadTaskCache(basePath, jobName string, force bool) *taskCache {
+	cache := &taskCache{
+		basePath:     basePath,
+		jobName:      jobName,
+		force:        force,
+		fingerprints: make(map[string]string),
+	}
+
+	// An unreadable store is the same as an empty one: the tasks are run again
+	content, err := os.ReadFile(filepath.Join(basePath, CACHE_DIR, FINGERPRINTS_FILE))
+	if err == nil {
+		_ = json.Unmarshal(content, &cache.fingerprints)
+	}
+
+	return cache
+}
+
+func (c *taskCache) key(stepName, taskName string) string {
+	return fmt.Sprintf("%s › %s › %s", c.jobName, stepName, taskName)
+}
+
+// Returns whether the task is up to date, given its current fingerprint
+func (c *taskCache) isUpToDate(stepName string, task cfg.TaskConfig, fingerprint string) bool {
+	if c.force || len(task.Sources) == 0 {
+		return false
+	}
+
+	c.mtx.Lock()
+	storedFingerprint, ok := c.fingerprints[c.key(stepName, task.Name)]
+	c.mtx.Unlock()
+	if !ok || storedFingerprint != fingerprint {
+		return false
+	}
+
+	for _, pattern := range task.Generates {
+		generated, err := expandGlob(c.basePath, pattern)
+		if err != nil || len(generated) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Saves the fingerprint of a task that was successful. It is the one computed before the task ran,
+// so that the sources changed during the run make the task out of date for the next one.
+func (c *taskCache) store(stepName string, task cfg.TaskConfig, fingerprint string) error {
+	if len(task.Sources) == 0 {
+		return nil
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.fingerprints[c.key(stepName, task.Name)] = fingerprint
+	return c.save()
+}
+
+// Writes the store in the cache directory. The caller must hold the mutex.
+func (c *taskCache) save() error {
+	cacheDir := filepath.Join(c.basePath, CACHE_DIR)
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return err
+	}
+
+	// The cache directory is ignored by git, without having to change the .gitignore of the project
+	gitignorePath := filepath.Join(cacheDir, CACHE_GITIGNORE)
+	if _, err := os.Stat(gitignorePath); errors.Is(err, fs.ErrNotExist) {
+		if err := os.WriteFile(gitignorePath, []byte("*\n"), 0o644); err != nil {
+			return err
+		}
+	}
+
+	content, err := json.MarshalIndent(c.fingerprints, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write then rename, so that an interrupted run never leaves a truncated store
+	tmpFile, err := os.CreateTemp(cacheDir, FINGERPRINTS_FILE+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(content); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), filepath.Join(cacheDir, FINGERPRINTS_FILE))
+}
+
+// Computes the hash of the configuration of the task, of its environment and of the content of its sources.
+// The configuration contains the command, the hooks and the generated files. Only the name, the needs,
+// the conditions and continue_on_error are left out, since they do not change what the task does.
+// The env lists the environments inherited by the task, they contain the parameters of the job,
+// so that a run with other values is not up to date. The tasks without sources have no fingerprint.
+func (c *taskCache) fingerprint(task cfg.TaskConfig, env []cfg.EnvConfig) (string, error) {
+	if len(task.Sources) == 0 {
+		return "", nil
+	}
+
+	runConfig := task
+	runConfig.Name, runConfig.Needs, runConfig.ContinueOnError = "", nil, false
+	runConfig.ConditionsConfig = cfg.ConditionsConfig{}
+	encodedConfig, err := json.Marshal(runConfig)
+	if err != nil {
+		return "", err
+	}
+
+	resolvedEnv, err := cfg.ResolveEnv(c.basePath, append(slices.Clone(env), task.EnvConfig)...)
+	if err != nil {
+		return "", err
+	}
+
+	sources := make([]string, 0)
+	for _, pattern := range task.Sources {
+		matches, err := expandGlob(c.basePath, pattern)
+		if err != nil {
+			return "", err
+		}
+		sources = append(sources, matches...)
+	}
+	slices.Sort(sources)
+	sources = slices.Compact(sources)
+
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%s\x00%s\x00", FINGERPRINT_FORMAT, encodedConfig)
+	for _, variable := range resolvedEnv {
+		_, _ = fmt.Fprintf(hash, "%s\x00", variable)
+	}
+	_, _ = hash.Write([]byte{0})
+	for _, source := range sources {
+		relativePath, err := filepath.Rel(c.basePath, source)
+		if err != nil {
+			relativePath = source
+		}
+		_, _ = fmt.Fprintf(hash, "%s\x00", relativePath)
+
+		file, err := os.Open(source)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(hash, file)
+		_ = file.Close()
+		if err != nil {
+			return "", err
+		}
+		_, _ = hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Returns the regular files matching the pattern, which is resolved from the base path if it is relative.
+// On top of the syntax of filepath.Match, a "**" segment matches any number of directories.
+func expandGlob(basePath, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(basePath, pattern)
+	}
+
+	if !strings.Contains(pattern, "**") {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return slices.DeleteFunc(matches, func(match string) bool {
+			info, err := os.Stat(match)
+			return err != nil || !info.Mode().IsRegular()
+		}), nil
+	}
+
+	// Walk from the deepest directory that has no wildcard
+	root, patternSegments := splitGlob(pattern)
+
+	matches := make([]string, 0)
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		matched, err := matchSegments(patternSegments, strings.Split(filepath.ToSlash(relativePath), "/"))
+		if err != nil {
+			return err
+		}
+		if matched {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+
+	return matches, err
+}
+
+// Returns whether the path matches the pattern, which is resolved from the base path if it is relative
+func matchGlob(basePath, pattern, path string) bool {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(basePath, pattern)
+	}
+
+	matched, err := matchSegments(strings.Split(filepath.ToSlash(pattern), "/"), strings.Split(filepath.ToSlash(path), "/"))
+	return err == nil && matched
+}
+
+// Splits the pattern into the deepest directory that has no wildcard, from which the matching files can be found,
+// and the segments of the pattern after it
+func splitGlob(pattern string) (string, []string) {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	rootSegments := 0
+	for rootSegments < len(segments) && !strings.ContainsAny(segments[rootSegments], "*?[\\") {
+		rootSegments++
+	}
+
+	root := filepath.FromSlash(strings.Join(segments[:rootSegments], "/"))
+	if len(root) == 0 {
+		root = "/"
+	}
+	return root, segments[rootSegments:]
+}
+
+// Matches the segments of a path against the segments of a pattern, where "**" matches any number of segments
+func matchSegments(pattern, path []string) (bool, error) {
+	if len(pattern) == 0 {
+		return len(path) == 0, nil
+	}
+
+	if pattern[0] == "**" {
+		for skipped := 0; skipped <= len(path); skipped++ {
+			matched, err := matchSegments(pattern[1:], path[skipped:])
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+		return false, nil
+	}
+
+	if len(path) == 0 {
+		return false, nil
+	}
+	matched, err := filepath.Match(pattern[0], path[0])
+	if err != nil || !matched {
+		return false, err
+	}
+
+	return matchSegments(pattern[1:], path[1:])
+}
@@ -0,0 +1,192 @@
+package jobexec
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/corentindeboisset/tera/pkg/cfg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandGlob(t *testing.T) {
+	t.Parallel()
+
+	basePath := t.TempDir()
+	for _, path := range []string{"main.go", "pkg/a.go", "pkg/sub/b.go", "pkg/sub/c.txt"} {
+		require.Nil(t, os.MkdirAll(filepath.Dir(filepath.Join(basePath, path)), 0o755))
+		require.Nil(t, os.WriteFile(filepath.Join(basePath, path), []byte(path), 0o644))
+	}
+
+	matches, err := expandGlob(basePath, "*.go")
+	require.Nil(t, err)
+	require.Equal(t, []string{filepath.Join(basePath, "main.go")}, matches)
+
+	matches, err = expandGlob(basePath, "pkg/**/*.go")
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{filepath.Join(basePath, "pkg/a.go"), filepath.Join(basePath, "pkg/sub/b.go")}, matches)
+
+	matches, err = expandGlob(basePath, "**/*.txt")
+	require.Nil(t, err)
+	require.Equal(t, []string{filepath.Join(basePath, "pkg/sub/c.txt")}, matches)
+
+	// The directories are not matched
+	matches, err = expandGlob(basePath, "pkg/*")
+	require.Nil(t, err)
+	require.Equal(t, []string{filepath.Join(basePath, "pkg/a.go")}, matches)
+
+	matches, err = expandGlob(basePath, "missing/**/*.go")
+	require.Nil(t, err)
+	require.Empty(t, matches)
+}
+
+func TestTaskCache(t *testing.T) {
+	t.Parallel()
+
+	basePath := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(basePath, "schema.json"), []byte("{}"), 0o644))
+
+	task := cfg.TaskConfig{
+		CmdConfig: cfg.CmdConfig{Cmd: "generate > generated.go"},
+		Name:      "codegen",
+		Sources:   []string{"*.json"},
+		Generates: []string{"generated.go"},
+	}
+
+	env := []cfg.EnvConfig{{Env: map[string]string{"TERA_PARAM_MODE": "debug"}}}
+	isUpToDate := func(cache *taskCache, task cfg.TaskConfig, env []cfg.EnvConfig) bool {
+		fingerprint, err := cache.fingerprint(task, env)
+		require.Nil(t, err)
+		return cache.isUpToDate("generate", task, fingerprint)
+	}
+
+	cache := loadTaskCache(basePath, "build", false)
+	require.False(t, isUpToDate(cache, task, env))
+	fingerprint, err := cache.fingerprint(task, env)
+	require.Nil(t, err)
+	require.Nil(t, cache.store("generate", task, fingerprint))
+
+	// The generated file is missing
+	require.False(t, isUpToDate(cache, task, env))
+	require.Nil(t, os.WriteFile(filepath.Join(basePath, "generated.go"), []byte("package main"), 0o644))
+	require.True(t, isUpToDate(cache, task, env))
+
+	// The fingerprints are kept between two runs, in a directory ignored by git
+	cache = loadTaskCache(basePath, "build", false)
+	require.True(t, isUpToDate(cache, task, env))
+	require.FileExists(t, filepath.Join(basePath, CACHE_DIR, CACHE_GITIGNORE))
+	require.False(t, isUpToDate(loadTaskCache(basePath, "build", true), task, env))
+	require.False(t, isUpToDate(loadTaskCache(basePath, "other job", false), task, env))
+
+	// A change of the command or of the sources makes the task out of date
+	changedTask := task
+	changedTask.Cmd = "generate --verbose > generated.go"
+	require.False(t, isUpToDate(cache, changedTask, env))
+
+	// So does a change of the parameters or of the environment
+	releaseEnv := []cfg.EnvConfig{{Env: map[string]string{"TERA_PARAM_MODE": "release"}}}
+	require.False(t, isUpToDate(cache, task, releaseEnv))
+	changedTask = task
+	changedTask.Env = map[string]string{"VERBOSE": "1"}
+	require.False(t, isUpToDate(cache, changedTask, env))
+
+	// Or a change of its hooks or of its generated files, but not of its name or its needs
+	changedTask = task
+	changedTask.RunAfter = []cfg.CmdConfig{{Cmd: "gofmt -w generated.go"}}
+	require.False(t, isUpToDate(cache, changedTask, env))
+	changedTask = task
+	changedTask.Generates = []string{"*.go"}
+	require.False(t, isUpToDate(cache, changedTask, env))
+	changedTask = task
+	changedTask.Needs = []string{"download"}
+	require.True(t, isUpToDate(cache, changedTask, env))
+
+	require.Nil(t, os.WriteFile(filepath.Join(basePath, "schema.json"), []byte(`{"type": "object"}`), 0o644))
+	require.False(t, isUpToDate(cache, task, env))
+}
+
+func TestSourcesChangedDuringRun(t *testing.T) {
+	t.Parallel()
+
+	configText := `
+jobs:
+  - name: build
+    steps:
+      - name: codegen
+        tasks:
+          - name: generate
+            cmd: cp schema.json generated.json && echo changed > schema.json
+            sources: [schema.json]
+            generates: [generated.json]
+`
+	config, err := cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+	job := &config.Jobs[0]
+
+	basePath := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(basePath, "schema.json"), []byte("{}"), 0o644))
+	runJob := func() *JobStatus {
+		jobStatus := &JobStatus{Steps: make([]StepStatus, len(job.Steps))}
+		readyToDisplay := make(chan struct{})
+		done := make(chan struct{})
+		go executeJob(context.Background(), basePath, config.EnvConfig, job, nil, loadTaskCache(basePath, job.Name, false), jobStatus, readyToDisplay, done)
+		<-readyToDisplay
+		<-done
+		return jobStatus
+	}
+
+	// The task changed its own sources, so the fingerprint stored after its success is outdated
+	require.Equal(t, STATE_SUCCESSFUL, runJob().Steps[0].Tasks[0].state)
+	require.Equal(t, STATE_SUCCESSFUL, runJob().Steps[0].Tasks[0].state)
+
+	// Once the sources are stable, the task is up to date
+	require.Equal(t, STATE_CACHED, runJob().Steps[0].Tasks[0].state)
+}
+
+func TestCancelledTaskNotCached(t *testing.T) {
+	t.Parallel()
+
+	// The task handles the stop signal and exits successfully, but it did not complete
+	configText := `
+jobs:
+  - name: build
+    steps:
+      - name: codegen
+        tasks:
+          - name: generate
+            cmd: trap 'exit 0' TERM; touch started; while true; do sleep 0.05; done
+            sources: [schema.json]
+`
+	config, err := cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+	job := &config.Jobs[0]
+
+	basePath := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(basePath, "schema.json"), []byte("{}"), 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for {
+			if _, err := os.Stat(filepath.Join(basePath, "started")); err == nil {
+				cancel()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	jobStatus := &JobStatus{Steps: make([]StepStatus, len(job.Steps))}
+	readyToDisplay := make(chan struct{})
+	done := make(chan struct{})
+	go executeJob(ctx, basePath, config.EnvConfig, job, nil, loadTaskCache(basePath, job.Name, false), jobStatus, readyToDisplay, done)
+	<-readyToDisplay
+	<-done
+
+	require.Equal(t, STATE_FAILED, jobStatus.Steps[0].Tasks[0].state)
+	_, err = os.Stat(filepath.Join(basePath, CACHE_DIR, FINGERPRINTS_FILE))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,293 @@
+package jobexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/corentindeboisset/tera/pkg/cfg"
+	"github.com/corentindeboisset/tera/pkg/cmdrunr"
+)
+
+// Duration without any change after which a burst of changes restarts the job
+const WATCH_DEBOUNCE = 300 * time.Millisecond
+
+// Returns the globs of the files that make the step run again: its watch globs and the sources of its tasks
+func stepWatchedGlobs(step cfg.StepConfig) []string {
+	globs := slices.Clone(step.Watch)
+	for _, task := range step.Tasks {
+		globs = append(globs, task.Sources...)
+	}
+
+	return globs
+}
+
+// Returns the indexes of the steps affected by the change of the file, whose watched files match it.
+// A change of the files watched by the job affects all its steps, and so does an empty path,
+// which means that any file may have changed.
+func affectedSteps(basePath string, config *cfg.JobConfig, path string) []int {
+	allSteps := make([]int, len(config.Steps))
+	for stepIdx := range config.Steps {
+		allSteps[stepIdx] = stepIdx
+	}
+	if len(path) == 0 {
+		return allSteps
+	}
+
+	for _, pattern := range config.Watch {
+		if matchGlob(basePath, pattern, path) {
+			return allSteps
+		}
+	}
+
+	steps := make([]int, 0)
+	for stepIdx, step := range config.Steps {
+		if slices.ContainsFunc(stepWatchedGlobs(step), func(pattern string) bool {
+			return matchGlob(basePath, pattern, path)
+		}) {
+			steps = append(steps, stepIdx)
+		}
+	}
+
+	return steps
+}
+
+// Returns which steps must run again: the given steps, and the steps that need them, directly or not
+func stepsToRerun(config *cfg.JobConfig, steps []int) []bool {
+	dependents := make([][]int, len(config.Steps))
+	for stepIdx, stepNeeds := range config.StepNeeds() {
+		for _, need := range stepNeeds {
+			dependents[need] = append(dependents[need], stepIdx)
+		}
+	}
+
+	toRun := make([]bool, len(config.Steps))
+	queue := slices.Clone(steps)
+	for len(queue) > 0 {
+		stepIdx := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if toRun[stepIdx] {
+			continue
+		}
+
+		toRun[stepIdx] = true
+		queue = append(queue, dependents[stepIdx]...)
+	}
+
+	return toRun
+}
+
+// Returns the directories to watch to see the changes of the files matched by the globs
+func watchRoots(basePath string, globs []string) []string {
+	roots := make([]string, 0, len(globs))
+	for _, pattern := range globs {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(basePath, pattern)
+		}
+
+		// The files that do not exist yet are seen from their closest existing directory,
+		// without going above the base path
+		root, _ := splitGlob(pattern)
+		for root != basePath {
+			info, err := os.Stat(root)
+			if err == nil && info.IsDir() {
+				break
+			}
+			parent := filepath.Dir(root)
+			if parent == root {
+				break
+			}
+			root = parent
+		}
+		roots = append(roots, root)
+	}
+
+	// The directories inside another one are already watched with it
+	slices.Sort(roots)
+	roots = slices.Compact(roots)
+	return slices.DeleteFunc(roots, func(root string) bool {
+		return slices.ContainsFunc(roots, func(other string) bool {
+			return other != root && strings.HasPrefix(root, strings.TrimSuffix(other, string(filepath.Separator))+string(filepath.Separator))
+		})
+	})
+}
+
+// Watches the files of the job until the context is cancelled. After each burst of changes,
+// the returned channel receives the sorted indexes of the steps affected by the changes.
+func watchJobFiles(ctx context.Context, basePath string, config *cfg.JobConfig) (<-chan []int, error) {
+	globs := slices.Clone(config.Watch)
+	for _, step := range config.Steps {
+		globs = append(globs, stepWatchedGlobs(step)...)
+	}
+	if len(globs) == 0 {
+		return nil, fmt.Errorf("the job \"%s\" has no files to watch: declare watch globs, or sources in its tasks", config.Name)
+	}
+
+	watcher, err := newFsWatcher()
+	if err != nil {
+		return nil, err
+	}
+	for _, root := range watchRoots(basePath, globs) {
+		if err := watcher.AddTree(root); err != nil {
+			_ = watcher.Close()
+			return nil, fmt.Errorf("the directory \"%s\" cannot be watched: %w", root, err)
+		}
+	}
+
+	changedSteps := make(chan []int)
+	go func() {
+		defer close(changedSteps)
+
+		debounce := time.NewTimer(WATCH_DEBOUNCE)
+		debounce.Stop()
+		pendingSteps := make([]int, 0)
+
+		changes := watcher.Changes()
+		for {
+			select {
+			case <-ctx.Done():
+				// Drain the changes until the watcher is closed, so that it is never blocked
+				_ = watcher.Close()
+				for range changes {
+				}
+				return
+
+			case path, ok := <-changes:
+				if !ok {
+					return
+				}
+				steps := affectedSteps(basePath, config, path)
+				if len(steps) == 0 {
+					continue
+				}
+				pendingSteps = append(pendingSteps, steps...)
+				debounce.Reset(WATCH_DEBOUNCE)
+
+			case <-debounce.C:
+				slices.Sort(pendingSteps)
+				select {
+				case changedSteps <- slices.Compact(pendingSteps):
+					pendingSteps = make([]int, 0)
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+
+	return changedSteps, nil
+}
+
+// Keeps the output of the last run as the previous output, and empties the current one
+func archiveOutput(output, previousOutput *cmdrunr.SafeBuffer) {
+	previousOutput.Reset()
+	_, _ = previousOutput.Write(output.Bytes())
+	output.Reset()
+}
+
+// Returns the indexes of the steps that were not successful in the last run
+func incompleteSteps(jobStatus *JobStatus) []int {
+	steps := make([]int, 0)
+	for stepIdx := range jobStatus.Steps {
+		jobStatus.Steps[stepIdx].Mtx.Lock()
+		state := jobStatus.Steps[stepIdx].state
+		jobStatus.Steps[stepIdx].Mtx.Unlock()
+
+		if state != STATE_SUCCESSFUL && state != STATE_SKIPPED {
+			steps = append(steps, stepIdx)
+		}
+	}
+
+	return steps
+}
+
+// Resets the statuses of the steps to run again, and of the run_after hooks of the job.
+// Their outputs are kept as the outputs of the previous run.
+func resetJobStatus(jobStatus *JobStatus, toRun []bool) {
+	for stepIdx := range jobStatus.Steps {
+		if !toRun[stepIdx] {
+			continue
+		}
+
+		stepStatus := &jobStatus.Steps[stepIdx]
+		stepStatus.Mtx.Lock()
+		stepStatus.state = STATE_NOT_STARTED
+		for _, hooks := range []*CmdStatus{stepStatus.BeforeHooks, stepStatus.AfterHooks} {
+			if hooks != nil {
+				hooks.state = STATE_NOT_STARTED
+				archiveOutput(&hooks.Output, &hooks.PreviousOutput)
+			}
+		}
+		for taskIdx := range stepStatus.Tasks {
+			taskStatus := &stepStatus.Tasks[taskIdx]
+			taskStatus.state = STATE_NOT_STARTED
+			taskStatus.BeforeHooksSuccess = false
+			taskStatus.MainTaskStatus = false
+			taskStatus.AfterHooksSuccess = false
+			taskStatus.ErrorIgnored = false
+			taskStatus.Attempt = 0
+			archiveOutput(&taskStatus.Output, &taskStatus.PreviousOutput)
+		}
+		stepStatus.Mtx.Unlock()
+	}
+
+	jobStatus.Mtx.Lock()
+	if jobStatus.AfterHooks != nil {
+		jobStatus.AfterHooks.state = STATE_NOT_STARTED
+		archiveOutput(&jobStatus.AfterHooks.Output, &jobStatus.AfterHooks.PreviousOutput)
+	}
+	jobStatus.result = ""
+	jobStatus.Mtx.Unlock()
+}
+
+// Runs the job again for the steps affected by the changes, and the steps that were not successful in the previous run,
+// along with all the steps that need them. The done channel is closed at the end of the run.
+func restartJob(ctx context.Context, basePath string, globalEnv cfg.EnvConfig, config *cfg.JobConfig, params map[string]string, jobStatus *JobStatus, changedSteps []int, done chan struct{}) {
+	defer close(done)
+
+	// The watched files are not always sources of the tasks, so the steps that run again are never up to date
+	cache := loadTaskCache(basePath, config.Name, true)
+
+	toRun := stepsToRerun(config, append(slices.Clone(changedSteps), incompleteSteps(jobStatus)...))
+	resetJobStatus(jobStatus, toRun)
+	runJob(ctx, basePath, globalEnv, config, params, cache, jobStatus, toRun)
+}
+
+// Restarts the job each time its files change, until the context is cancelled. The run in progress is cancelled
+// before the job restarts. The done channel is closed once the context is cancelled and the last run is over.
+func rerunOnChanges(ctx context.Context, changedSteps <-chan []int, cancelRun context.CancelFunc, runDone chan struct{}, restart func(ctx context.Context, changedSteps []int, done chan struct{}), done chan struct{}) {
+	defer close(done)
+
+	startRun := func(steps []int) (context.CancelFunc, chan struct{}) {
+		runCtx, cancel := context.WithCancel(ctx)
+		runDone := make(chan struct{})
+		go restart(runCtx, steps, runDone)
+		return cancel, runDone
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			<-runDone
+			cancelRun()
+			return
+
+		case steps, ok := <-changedSteps:
+			if !ok {
+				<-runDone
+				cancelRun()
+				return
+			}
+
+			cancelRun()
+			<-runDone
+			cancelRun, runDone = startRun(steps)
+		}
+	}
+}
+
+var errWatchWithoutTui = errors.New("the watch mode needs the interactive interface, it cannot be used with --no-tui or without a terminal")
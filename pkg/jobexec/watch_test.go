@@ -0,0 +1,176 @@
+package jobexec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/corentindeboisset/tera/pkg/cfg"
+	"github.com/stretchr/testify/require"
+)
+
+const watchTestConfig = `
+jobs:
+  - name: watched
+    watch: [tera.yml]
+    steps:
+      - name: generate
+        tasks:
+          - name: codegen
+            cmd: echo "generation $(cat schema/api.json)"
+            sources: ["schema/*.json"]
+      - name: test
+        watch: ["src/**/*.go"]
+        tasks:
+          - name: unit
+            cmd: echo "tests of $(cat src/pkg/main.go)"
+            sources: ["src/pkg/main.go"]
+`
+
+func TestAffectedSteps(t *testing.T) {
+	t.Parallel()
+
+	config, err := cfg.ParseConfig([]byte(watchTestConfig))
+	require.Nil(t, err)
+	job := &config.Jobs[0]
+
+	basePath := "/project"
+	require.Equal(t, []int{0, 1}, affectedSteps(basePath, job, "/project/tera.yml"))
+	require.Equal(t, []int{0}, affectedSteps(basePath, job, "/project/schema/api.json"))
+	require.Equal(t, []int{1}, affectedSteps(basePath, job, "/project/src/main.go"))
+	require.Equal(t, []int{1}, affectedSteps(basePath, job, "/project/src/pkg/sub/file.go"))
+	require.Empty(t, affectedSteps(basePath, job, "/project/src/README.md"))
+	require.Empty(t, affectedSteps(basePath, job, "/project/schema/nested/api.json"))
+
+	// The events may have been lost, so any file may have changed
+	require.Equal(t, []int{0, 1}, affectedSteps(basePath, job, ""))
+
+	// The steps that follow a changed step run again with it
+	require.Equal(t, []bool{false, true}, stepsToRerun(job, []int{1}))
+	require.Equal(t, []bool{true, true}, stepsToRerun(job, []int{0}))
+
+	// The needs can point to a later step: the steps that need a changed step run again, whatever their position
+	forwardNeedsConfig := `
+jobs:
+  - name: forward needs
+    steps:
+      - name: integration
+        needs: [build]
+        tasks:
+          - name: test
+            cmd: echo test
+      - name: build
+        tasks:
+          - name: compile
+            cmd: echo compile
+            sources: ["src/*.go"]
+      - name: lint
+        tasks:
+          - name: vet
+            cmd: echo vet
+`
+	config, err = cfg.ParseConfig([]byte(forwardNeedsConfig))
+	require.Nil(t, err)
+	forwardJob := &config.Jobs[0]
+	require.Equal(t, []int{1}, affectedSteps(basePath, forwardJob, "/project/src/main.go"))
+	require.Equal(t, []bool{true, true, false}, stepsToRerun(forwardJob, []int{1}))
+	require.Equal(t, []bool{true, false, false}, stepsToRerun(forwardJob, []int{0}))
+
+	// The directories that do not exist are watched from their closest existing parent
+	watchedPath := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(watchedPath, "src", "pkg"), 0o755))
+	require.Equal(t, []string{filepath.Join(watchedPath, "src")}, watchRoots(watchedPath, []string{"src/**/*.go", "src/pkg/*.go", "src/missing/*.go"}))
+	require.Equal(t, []string{watchedPath}, watchRoots(watchedPath, []string{"tera.yml", "src/**/*.go"}))
+	require.Equal(t, []string{watchedPath}, watchRoots(watchedPath, []string{"missing/*.json"}))
+}
+
+func TestWatchRestart(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("the watch mode is only supported on Linux")
+	}
+
+	basePath := t.TempDir()
+	writeFile := func(path, content string) {
+		require.Nil(t, os.MkdirAll(filepath.Dir(filepath.Join(basePath, path)), 0o755))
+		require.Nil(t, os.WriteFile(filepath.Join(basePath, path), []byte(content), 0o644))
+	}
+	writeFile("schema/api.json", "v1")
+	writeFile("src/pkg/main.go", "main v1")
+
+	config, err := cfg.ParseConfig([]byte(watchTestConfig))
+	require.Nil(t, err)
+	job := &config.Jobs[0]
+	jobStatus := &JobStatus{Steps: make([]StepStatus, len(job.Steps))}
+	cache := loadTaskCache(basePath, job.Name, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changedSteps, err := watchJobFiles(ctx, basePath, job)
+	require.Nil(t, err)
+
+	readyToDisplay := make(chan struct{})
+	firstRunDone := make(chan struct{})
+	runCtx, cancelRun := context.WithCancel(ctx)
+	go executeJob(runCtx, basePath, config.EnvConfig, job, nil, cache, jobStatus, readyToDisplay, firstRunDone)
+	<-readyToDisplay
+	<-firstRunDone
+	require.Equal(t, JOB_RESULT_SUCCESS, jobStatus.Result())
+
+	// Forward the restarts to the test, to wait for them
+	restarts := make(chan []int)
+	restart := func(runCtx context.Context, steps []int, done chan struct{}) {
+		restartJob(runCtx, basePath, config.EnvConfig, job, nil, jobStatus, steps, done)
+		restarts <- steps
+	}
+	done := make(chan struct{})
+	go rerunOnChanges(ctx, changedSteps, cancelRun, firstRunDone, restart, done)
+
+	// A burst of changes in the second step only restarts the job once, for the second step
+	writeFile("src/pkg/main.go", "main v2")
+	writeFile("src/pkg/other.go", "other")
+	select {
+	case steps := <-restarts:
+		require.Equal(t, []int{1}, steps)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the job was not restarted")
+	}
+	require.Contains(t, string(jobStatus.Steps[0].Tasks[0].Output.Bytes()), "generation v1")
+	require.Contains(t, string(jobStatus.Steps[1].Tasks[0].Output.Bytes()), "tests of main v2")
+	require.Contains(t, string(jobStatus.Steps[1].Tasks[0].PreviousOutput.Bytes()), "tests of main v1")
+	select {
+	case <-restarts:
+		require.Fail(t, "the burst of changes restarted the job twice")
+	case <-time.After(2 * WATCH_DEBOUNCE):
+	}
+
+	// The tasks of the restarted steps run even if the changed files are not in their sources
+	writeFile("src/pkg/other.go", "other v2")
+	select {
+	case steps := <-restarts:
+		require.Equal(t, []int{1}, steps)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the job was not restarted")
+	}
+	require.Equal(t, STATE_SUCCESSFUL, jobStatus.Steps[1].Tasks[0].state)
+	require.Contains(t, string(jobStatus.Steps[1].Tasks[0].Output.Bytes()), "tests of main v2")
+
+	// A change of the sources of the first step restarts the whole job
+	writeFile("schema/api.json", "v2")
+	select {
+	case steps := <-restarts:
+		require.Equal(t, []int{0}, steps)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the job was not restarted")
+	}
+	require.Contains(t, string(jobStatus.Steps[0].Tasks[0].Output.Bytes()), "generation v2")
+	require.Equal(t, JOB_RESULT_SUCCESS, jobStatus.Result())
+
+	cancel()
+	<-done
+}
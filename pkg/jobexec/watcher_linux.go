@@ -0,0 +1,129 @@
+package jobexec
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const WATCH_EVENTS = unix.IN_CLOSE_WRITE | unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO
+
+// Watches directory trees with inotify, and sends the path of every file that changes in them
+type fsWatcher struct {
+	file    *os.File
+	changes chan string
+
+	watches map[int]string
+	mtx     sync.Mutex
+}
+
+func newFsWatcher() (*fsWatcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &fsWatcher{
+		// The descriptor is non-blocking, so the reads go through the runtime poller and are interrupted by Close
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		changes: make(chan string),
+		watches: make(map[int]string),
+	}
+	go w.readEvents()
+
+	return w, nil
+}
+
+// Returns the channel of the changed paths. It is closed when the watcher is closed.
+func (w *fsWatcher) Changes() <-chan string {
+	return w.changes
+}
+
+func (w *fsWatcher) Close() error {
+	return w.file.Close()
+}
+
+// Watches the directory and all its subdirectories, except the ones of git and of tera
+func (w *fsWatcher) AddTree(root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+				return nil
+			}
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path != root && (entry.Name() == ".git" || entry.Name() == CACHE_DIR) {
+			return filepath.SkipDir
+		}
+
+		wd, err := unix.InotifyAddWatch(int(w.file.Fd()), path, WATCH_EVENTS)
+		if err != nil {
+			return err
+		}
+		w.mtx.Lock()
+		w.watches[wd] = path
+		w.mtx.Unlock()
+
+		return nil
+	})
+}
+
+func (w *fsWatcher) readEvents() {
+	defer close(w.changes)
+
+	buffer := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buffer)
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			nameBytes := buffer[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+			offset += unix.SizeofInotifyEvent + int(event.Len)
+
+			w.mtx.Lock()
+			dir, ok := w.watches[int(event.Wd)]
+			if event.Mask&unix.IN_IGNORED != 0 {
+				delete(w.watches, int(event.Wd))
+			}
+			w.mtx.Unlock()
+
+			if event.Mask&unix.IN_Q_OVERFLOW != 0 {
+				// Some events were lost, so anything may have changed
+				w.changes <- ""
+				continue
+			}
+			if !ok || len(nameBytes) == 0 {
+				continue
+			}
+
+			path := filepath.Join(dir, string(bytes.TrimRight(nameBytes, "\x00")))
+			if event.Mask&unix.IN_ISDIR != 0 {
+				// The new directories are watched too, and the files that were moved with them are changes
+				if event.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+					_ = w.AddTree(path)
+					_ = filepath.WalkDir(path, func(subPath string, entry fs.DirEntry, err error) error {
+						if err == nil && !entry.IsDir() {
+							w.changes <- subPath
+						}
+						return nil
+					})
+				}
+				continue
+			}
+
+			w.changes <- path
+		}
+	}
+}
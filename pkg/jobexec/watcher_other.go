@@ -0,0 +1,24 @@
+//go:build !linux
+
+package jobexec
+
+import "errors"
+
+// The watch mode relies on inotify, which only exists on Linux
+type fsWatcher struct{}
+
+func newFsWatcher() (*fsWatcher, error) {
+	return nil, errors.New("the watch mode is only supported on Linux")
+}
+
+func (w *fsWatcher) Changes() <-chan string {
+	return nil
+}
+
+func (w *fsWatcher) Close() error {
+	return nil
+}
+
+func (w *fsWatcher) AddTree(root string) error {
+	return nil
+}
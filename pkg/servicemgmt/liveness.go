@@ -0,0 +1,124 @@
+package servicemgmt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/corentindeboisset/tera/pkg/cmdrunr"
+)
+
+var ErrUnhealthy = errors.New("the service is unhealthy")
+
+// Values of the liveness configuration used when they are not set
+const (
+	DEFAULT_LIVENESS_INTERVAL          = 10 * time.Second
+	DEFAULT_LIVENESS_FAILURE_THRESHOLD = 3
+)
+
+type ProbeResult struct {
+	Ok                  bool
+	Latency             time.Duration
+	ConsecutiveFailures int
+}
+
+// Runs every configured healthcheck once (except the output one, that only makes sense during the startup),
+// and returns true if they all passed within the timeout of the liveness probe
+func (s *ManagedService) checkLiveness(ctx context.Context) bool {
+	healthcheck := s.Config.Healthcheck
+	timeout := healthcheck.Liveness.Timeout
+	if timeout == 0 {
+		timeout = cmdrunr.PROBE_ATTEMPT_TIMEOUT
+	}
+
+	probeContext, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if healthcheck.Port > 0 && !cmdrunr.CheckPort(probeContext, healthcheck.Port) {
+		return false
+	}
+
+	if len(healthcheck.Http.Url) > 0 {
+		check := cmdrunr.HttpCheck{
+			Url:            healthcheck.Http.Url,
+			Method:         healthcheck.Http.Method,
+			ExpectedStatus: healthcheck.Http.ExpectedStatus,
+			Insecure:       healthcheck.Http.Insecure,
+		}
+		if len(healthcheck.Http.BodyRegex) > 0 {
+			check.BodyRegex = regexp.MustCompile(healthcheck.Http.BodyRegex)
+		}
+		if !cmdrunr.CheckHttp(probeContext, check) {
+			return false
+		}
+	}
+
+	if len(healthcheck.File.Path) > 0 && !cmdrunr.CheckFile(s.BasePath, healthcheck.File.Path) {
+		return false
+	}
+
+	if len(healthcheck.Cmd.Cmd) > 0 && !cmdrunr.CheckCmd(probeContext, s.BasePath, s.Config.Path, healthcheck.Cmd.Cmd, s.serviceEnv()) {
+		return false
+	}
+
+	return true
+}
+
+// Keeps probing the service until the context is done. After too many consecutive failures, the service is marked as
+// unhealthy, and it is stopped if the liveness configuration requires a restart.
+func (s *ManagedService) monitorLiveness(ctx context.Context, cancel context.CancelCauseFunc) {
+	liveness := s.Config.Healthcheck.Liveness
+	interval := liveness.Interval
+	if interval == 0 {
+		interval = DEFAULT_LIVENESS_INTERVAL
+	}
+	threshold := liveness.FailureThreshold
+	if threshold == 0 {
+		threshold = DEFAULT_LIVENESS_FAILURE_THRESHOLD
+	}
+
+	consecutiveFailures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		probeStart := time.Now()
+		ok := s.checkLiveness(ctx)
+		latency := time.Since(probeStart)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if ok {
+			consecutiveFailures = 0
+		} else {
+			consecutiveFailures++
+		}
+
+		s.StateMtx.Lock()
+		s.LastProbe = &ProbeResult{Ok: ok, Latency: latency, ConsecutiveFailures: consecutiveFailures}
+
+		if ok && s.State == SERVICE_UNHEALTHY {
+			log.Printf("The service %s is healthy again", s.Id)
+			_, _ = fmt.Fprintf(&s.Output, "\nThe liveness probe passed again, the service is healthy\n")
+			s.State = SERVICE_RUNNING
+		} else if !ok && consecutiveFailures >= threshold && s.State == SERVICE_RUNNING {
+			log.Printf("The service %s is unhealthy", s.Id)
+			_, _ = fmt.Fprintf(&s.Output, "\nThe liveness probe failed %d times in a row, the service is unhealthy\n", consecutiveFailures)
+			s.State = SERVICE_UNHEALTHY
+
+			if liveness.Restart {
+				_, _ = fmt.Fprintf(&s.Output, "The service is stopped to be restarted\n")
+				cancel(ErrUnhealthy)
+			}
+		}
+		s.StateMtx.Unlock()
+	}
+}
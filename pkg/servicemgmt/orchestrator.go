@@ -2,12 +2,16 @@ package servicemgmt
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"maps"
+	"math"
+	"regexp"
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/corentindeboisset/tera/pkg/cfg"
 	"github.com/corentindeboisset/tera/pkg/cmdrunr"
@@ -21,6 +25,27 @@ const (
 	SERVICE_FAILED_DEPENDENCY
 	SERVICE_RUNNING
 	SERVICE_ERROR
+	SERVICE_BACKOFF
+	SERVICE_CRASH_LOOP
+	SERVICE_UNHEALTHY
+)
+
+// Values of the restart policy used when they are not set in the configuration
+const (
+	DEFAULT_RESTART_INITIAL_DELAY = 1 * time.Second
+	DEFAULT_RESTART_MULTIPLIER    = 2.0
+	DEFAULT_RESTART_MAX_DELAY     = 30 * time.Second
+	DEFAULT_RESTART_MAX_COUNT     = 5
+	DEFAULT_RESTART_WINDOW        = 1 * time.Minute
+
+	STOP_CMD_TIMEOUT = 2 * time.Minute
+
+	// Duration after which a run_after hook is stopped, since it is not stopped along with the service
+	AFTER_HOOK_TIMEOUT = 2 * time.Minute
+
+	// Size given to the commands until the interface gives the size of the output panel
+	DEFAULT_OUTPUT_WIDTH  = 120
+	DEFAULT_OUTPUT_HEIGHT = 24
 )
 
 type ServiceDependency struct {
@@ -33,15 +58,32 @@ type ManagedService struct {
 	Id string
 
 	BasePath     string
+	GlobalEnv    cfg.EnvConfig
 	Config       cfg.ServiceConfig
 	Dependencies []*ServiceDependency
 
+	// Services that declared a dependency on this one with restart_with_target
+	cascadeDependents []*ManagedService
+
 	ctx    context.Context
 	cancel context.CancelCauseFunc
 	Output cmdrunr.SafeBuffer
 
 	openPending bool
 
+	// Size of the output panel, followed by the commands that run in a pseudo-terminal
+	OutputSize *cmdrunr.TerminalSize
+
+	// Whether the stop_cmd is running
+	stopping bool
+
+	// Number of automatic restarts since tera was started, and the dates of the recent ones
+	RestartCount   int
+	restartHistory []time.Time
+
+	// Result of the last liveness probe of the current execution, nil if there was none
+	LastProbe *ProbeResult
+
 	State           ServiceState
 	StateMtx        sync.Mutex
 	DoneCond        *sync.Cond
@@ -55,15 +97,17 @@ type Orchestrator struct {
 	ServiceList map[string]*ManagedService
 }
 
-func NewOrchestrator(basePath string, serviceConfigList map[string]cfg.ServiceConfig) (*Orchestrator, error) {
+func NewOrchestrator(basePath string, globalEnv cfg.EnvConfig, serviceConfigList map[string]cfg.ServiceConfig) (*Orchestrator, error) {
 	serviceList := make(map[string]*ManagedService)
 	dependencyConfigs := make(map[string]*[]cfg.DependencyConfig)
 	for serviceId, serviceConfig := range serviceConfigList {
 		serviceList[serviceId] = &ManagedService{
 			Id:           serviceId,
 			BasePath:     basePath,
+			GlobalEnv:    globalEnv,
 			Config:       serviceConfig,
 			Dependencies: make([]*ServiceDependency, 0),
+			OutputSize:   cmdrunr.NewTerminalSize(DEFAULT_OUTPUT_WIDTH, DEFAULT_OUTPUT_HEIGHT),
 			State:        SERVICE_OFF,
 		}
 		serviceList[serviceId].DoneCond = sync.NewCond(&serviceList[serviceId].StateMtx)
@@ -87,6 +131,9 @@ func NewOrchestrator(basePath string, serviceConfigList map[string]cfg.ServiceCo
 						WaitTargetStarted: dependencyConfig.WaitTargetStarted,
 					}
 					service.Dependencies = append(service.Dependencies, dependency)
+					if dependency.RestartWithTarget {
+						targetCandidate.cascadeDependents = append(targetCandidate.cascadeDependents, service)
+					}
 					foundDependency = true
 				}
 			}
@@ -161,6 +208,18 @@ func (o *Orchestrator) SortedServices() []*ManagedService {
 // Kill all services, wait for all process to end and return.
 // If you call it in a goroutine, you can should a channel as an argument that will be closed once the shutdown is complete.
 func (o *Orchestrator) Shutdown(done chan any) {
+	// The services are killed first, so that their stop_cmd can run. A service is only killed once all the
+	// services that depend on it are off, the services of a same level are killed at once.
+	for _, level := range shutdownLevels(o.ServiceList) {
+		var wg sync.WaitGroup
+		for _, service := range level {
+			wg.Go(func() {
+				service.Kill(true)
+			})
+		}
+		wg.Wait()
+	}
+
 	o.cancel(cmdrunr.ErrPlannedKill)
 
 	// Wait for all services to be off
@@ -179,6 +238,43 @@ func (o *Orchestrator) Shutdown(done chan any) {
 	}
 }
 
+// Groups the services by the order in which they are stopped: the first level contains the services that
+// nothing depends on, and each next level the services whose dependents are all in the previous levels.
+// The dependency graph is acyclic, since it is checked when the orchestrator is created.
+func shutdownLevels(serviceList map[string]*ManagedService) [][]*ManagedService {
+	dependentCounts := make(map[string]int)
+	for _, service := range serviceList {
+		for _, dependency := range service.Dependencies {
+			dependentCounts[dependency.Target.Id]++
+		}
+	}
+
+	levels := make([][]*ManagedService, 0)
+	level := make([]*ManagedService, 0)
+	for serviceId, service := range serviceList {
+		if dependentCounts[serviceId] == 0 {
+			level = append(level, service)
+		}
+	}
+
+	for len(level) > 0 {
+		levels = append(levels, level)
+
+		nextLevel := make([]*ManagedService, 0)
+		for _, service := range level {
+			for _, dependency := range service.Dependencies {
+				dependentCounts[dependency.Target.Id]--
+				if dependentCounts[dependency.Target.Id] == 0 {
+					nextLevel = append(nextLevel, dependency.Target)
+				}
+			}
+		}
+		level = nextLevel
+	}
+
+	return levels
+}
+
 // Calls start on a given service
 func (o *Orchestrator) StartService(id string, outputWidth, outputHeight int) {
 	for _, service := range o.ServiceList {
@@ -209,6 +305,13 @@ func (o *Orchestrator) RestartService(id string, appOnly bool, outputWidth, outp
 	}
 }
 
+// Changes the size of the output of all the services, the commands running in a pseudo-terminal are notified
+func (o *Orchestrator) ResizeOutputs(outputWidth, outputHeight int) {
+	for _, service := range o.ServiceList {
+		service.OutputSize.Resize(outputWidth, outputHeight)
+	}
+}
+
 // Calls open on a given service
 func (o *Orchestrator) OpenService(id string) {
 	for _, service := range o.ServiceList {
@@ -222,7 +325,7 @@ func (o *Orchestrator) OpenService(id string) {
 // Check if the service is running.
 // Be careful, you should lock the service's StateMtx before calling this method
 func (s *ManagedService) IsInExecution() bool {
-	return s.State == SERVICE_RUNNING || s.State == SERVICE_STARTING
+	return s.State == SERVICE_RUNNING || s.State == SERVICE_STARTING || s.State == SERVICE_BACKOFF || s.State == SERVICE_UNHEALTHY
 }
 
 // This method starts all dependencies, waits for them to be up, then starts the service and returns
@@ -248,7 +351,7 @@ func (s *ManagedService) Start(baseCtx context.Context, outputWidth, outputHeigh
 				dependency.Target.StateMtx.Lock()
 				defer dependency.Target.StateMtx.Unlock()
 
-				for dependency.Target.State == SERVICE_STARTING {
+				for dependency.Target.State == SERVICE_STARTING || dependency.Target.State == SERVICE_BACKOFF {
 					dependency.Target.StartupOverCond.Wait()
 				}
 			})
@@ -256,6 +359,8 @@ func (s *ManagedService) Start(baseCtx context.Context, outputWidth, outputHeigh
 	}
 	wg.Wait()
 
+	failedDependency := s.findFailedDependency()
+
 	s.StateMtx.Lock()
 	defer s.StateMtx.Unlock()
 
@@ -263,54 +368,317 @@ func (s *ManagedService) Start(baseCtx context.Context, outputWidth, outputHeigh
 		return
 	}
 
-	s.ctx, s.cancel = context.WithCancelCause(baseCtx)
+	if failedDependency != nil {
+		log.Printf("The service %s cannot start, its dependency %s failed", s.Id, failedDependency.Id)
+		_, _ = fmt.Fprintf(&s.Output, "The service was not started because its dependency \"%s\" failed to start\n\n", failedDependency.Config.Name)
+		s.State = SERVICE_FAILED_DEPENDENCY
+		s.StartupOverCond.Broadcast()
+		return
+	}
+
+	// A manual start gives a fresh budget to the crash-loop detection
+	s.restartHistory = nil
+
+	s.OutputSize.Resize(outputWidth, outputHeight)
+	s.launch(baseCtx)
+}
+
+// Returns the first dependency declared with wait_target_restarted that failed to start or is not healthy, or nil if there are none
+func (s *ManagedService) findFailedDependency() *ManagedService {
+	for _, dependency := range s.Dependencies {
+		if !dependency.WaitTargetStarted {
+			continue
+		}
+
+		dependency.Target.StateMtx.Lock()
+		targetState := dependency.Target.State
+		dependency.Target.StateMtx.Unlock()
+
+		if targetState == SERVICE_ERROR || targetState == SERVICE_CRASH_LOOP || targetState == SERVICE_FAILED_DEPENDENCY || targetState == SERVICE_UNHEALTHY {
+			return dependency.Target
+		}
+	}
+
+	return nil
+}
+
+// Creates the context of a new execution, and starts the service in a goroutine.
+// Be careful, you should lock the service's StateMtx before calling this method
+func (s *ManagedService) launch(baseCtx context.Context) {
+	ctx, cancel := context.WithCancelCause(baseCtx)
+	s.ctx, s.cancel = ctx, cancel
 	s.State = SERVICE_STARTING
+	s.LastProbe = nil
 
-	go func() {
-		log.Printf("Starting the service %s", s.Id)
+	go s.run(baseCtx, ctx, cancel)
+}
 
-		healthcheckDone := make(chan any)
+// Runs the service command along with its healthcheck and hooks, and updates the state once it is over
+func (s *ManagedService) run(baseCtx, ctx context.Context, cancel context.CancelCauseFunc) {
+	log.Printf("Starting the service %s", s.Id)
+
+	ok := s.runHooks(ctx, s.Config.RunBefore)
+	interrupted := ctx.Err() != nil
+	if !ok {
+		log.Printf("A run_before hook of the service %s failed", s.Id)
+		_, _ = fmt.Fprintf(&s.Output, "\nA run_before hook failed, the service is not started\n")
+	} else if !interrupted {
+		ok, interrupted = s.runCommand(ctx, cancel)
+
+		// The post-stop hooks also run when the service was killed
+		if !s.runAfterHooks(ctx) {
+			log.Printf("A run_after hook of the service %s failed", s.Id)
+			_, _ = fmt.Fprintf(&s.Output, "\nA run_after hook failed\n")
+		}
+	}
 
-		// Start the healthcheck routine
-		go func() {
-			defer close(healthcheckDone)
+	// Post-run updates
+	s.StateMtx.Lock()
+	defer s.StateMtx.Unlock()
 
-			if s.Config.Healthcheck.Port > 0 {
-				if cmdrunr.WaitForPort(s.ctx, s.Config.Healthcheck.Port) {
-					log.Printf("Healthcheck status for service %s on Port %d ok", s.Id, s.Config.Healthcheck.Port)
-					s.onStartupSuccess()
-				} else {
-					log.Printf("Healthcheck failed for service %s", s.Id)
-				}
-			} else {
-				s.onStartupSuccess()
+	stoppedUnhealthy := errors.Is(context.Cause(ctx), ErrUnhealthy)
+
+	if ok || s.stopping {
+		// A service that exits because of its stop_cmd is not considered as crashed
+		s.State = SERVICE_OFF
+	} else if (s.Config.AutoRestart && !interrupted) || stoppedUnhealthy {
+		s.scheduleRestart(baseCtx)
+	} else {
+		s.State = SERVICE_ERROR
+	}
+
+	s.StartupOverCond.Broadcast()
+	s.DoneCond.Broadcast()
+}
+
+// Returns the environments of the service, inherited by its hooks, probes and stop_cmd
+func (s *ManagedService) serviceEnv() []cfg.EnvConfig {
+	return []cfg.EnvConfig{s.GlobalEnv, s.Config.FileEnv, s.Config.EnvConfig}
+}
+
+// Runs the hooks one after the other in the output of the service, and returns false as soon as one of them fails
+func (s *ManagedService) runHooks(ctx context.Context, hooks []cfg.CmdConfig) bool {
+	for _, hook := range hooks {
+		if !cmdrunr.RunCommandInTerminal(ctx, s.BasePath, hook, s.serviceEnv(), &s.Output, s.OutputSize) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Runs the run_after hooks one after the other in the output of the service, and returns false as soon as one of them fails.
+// They are not interrupted when the service is killed, but each of them is stopped and fails after AFTER_HOOK_TIMEOUT.
+func (s *ManagedService) runAfterHooks(ctx context.Context) bool {
+	for _, hook := range s.Config.RunAfter {
+		hookCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), AFTER_HOOK_TIMEOUT)
+		ok := cmdrunr.RunCommandInTerminal(hookCtx, s.BasePath, hook, s.serviceEnv(), &s.Output, s.OutputSize)
+		timedOut := hookCtx.Err() != nil
+		cancel()
+
+		if !ok {
+			if timedOut {
+				_, _ = fmt.Fprintf(&s.Output, "\nThe hook was stopped after running for %s\n", AFTER_HOOK_TIMEOUT)
 			}
+			return false
+		}
+	}
+
+	return true
+}
 
-			// TODO: add other healthchecks (file created, text in output...)
+// Runs the service command along with its healthcheck, and returns whether the command was successful,
+// and whether it was interrupted by the cancellation of the context
+func (s *ManagedService) runCommand(ctx context.Context, cancel context.CancelCauseFunc) (bool, bool) {
+	healthcheckDone := make(chan any)
 
-			log.Printf("Healthcheck finished for the service %s", s.Id)
-		}()
+	// The output healthcheck only considers what the command writes in this execution
+	outputOffset := s.Output.Len() + len(cmdrunr.CommandHeader(s.Config.Cmd))
 
-		// Start the service and wait for it to finish
-		ok := cmdrunr.RunCommand(s.ctx, s.BasePath, s.Config.Path, s.Config.Cmd, &s.Output, outputWidth, outputHeight)
+	// Start the healthcheck routine
+	go func() {
+		defer close(healthcheckDone)
 
-		log.Printf("The service %s has finished running", s.Id)
+		if s.waitForHealthcheck(ctx, outputOffset) {
+			log.Printf("Healthcheck status for service %s ok", s.Id)
+			s.onStartupSuccess()
 
-		// Ensure the healthcheck routine is finished
-		s.cancel(cmdrunr.ErrPlannedKill)
-		<-healthcheckDone
+			if s.Config.Healthcheck.Liveness.Enabled {
+				s.monitorLiveness(ctx, cancel)
+			}
+		} else if ctx.Err() == nil {
+			// The service never became healthy: it keeps running, but it is not ready for its dependents
+			log.Printf("Healthcheck failed for service %s", s.Id)
+			_, _ = fmt.Fprintf(&s.Output, "\nThe healthcheck failed, the service is not ready\n")
+			s.onStartupFailure()
+		}
 
-		// Post-run updates
-		s.StateMtx.Lock()
-		defer s.StateMtx.Unlock()
+		log.Printf("Healthcheck finished for the service %s", s.Id)
+	}()
 
-		if ok {
+	// Start the service and wait for it to finish
+	ok := cmdrunr.RunCommandInTerminal(ctx, s.BasePath, s.Config.CmdConfig, []cfg.EnvConfig{s.GlobalEnv, s.Config.FileEnv}, &s.Output, s.OutputSize)
+	interrupted := ctx.Err() != nil
+
+	log.Printf("The service %s has finished running", s.Id)
+
+	// Ensure the healthcheck routine is finished
+	cancel(cmdrunr.ErrPlannedKill)
+	<-healthcheckDone
+
+	return ok, interrupted
+}
+
+// Runs all the healthchecks configured for the service one after the other, and returns true if they all passed.
+// The output healthcheck only looks at the output written after the given offset.
+func (s *ManagedService) waitForHealthcheck(ctx context.Context, outputOffset int) bool {
+	healthcheck := s.Config.Healthcheck
+
+	// The port and output healthchecks have no timing of their own, they use the default one
+	if healthcheck.Port > 0 {
+		if !cmdrunr.WaitForPort(ctx, healthcheck.Port, cmdrunr.ProbeTiming{}) {
+			return false
+		}
+		log.Printf("Healthcheck status for service %s on Port %d ok", s.Id, healthcheck.Port)
+	}
+
+	if len(healthcheck.Http.Url) > 0 {
+		check := cmdrunr.HttpCheck{
+			Url:            healthcheck.Http.Url,
+			Method:         healthcheck.Http.Method,
+			ExpectedStatus: healthcheck.Http.ExpectedStatus,
+			Insecure:       healthcheck.Http.Insecure,
+		}
+		if len(healthcheck.Http.BodyRegex) > 0 {
+			// The regex has already been validated with the configuration
+			check.BodyRegex = regexp.MustCompile(healthcheck.Http.BodyRegex)
+		}
+
+		if !cmdrunr.WaitForHttp(ctx, check, probeTiming(healthcheck.Http.ProbeTimingConfig)) {
+			return false
+		}
+		log.Printf("Healthcheck status for service %s on url %s ok", s.Id, healthcheck.Http.Url)
+	}
+
+	if len(healthcheck.OutputRegex) > 0 {
+		// The regex has already been validated with the configuration
+		if !cmdrunr.WaitForOutput(ctx, &s.Output, outputOffset, regexp.MustCompile(healthcheck.OutputRegex), cmdrunr.ProbeTiming{}) {
+			return false
+		}
+		log.Printf("Healthcheck status for service %s on the output ok", s.Id)
+	}
+
+	if len(healthcheck.File.Path) > 0 {
+		if !cmdrunr.WaitForFile(ctx, s.BasePath, healthcheck.File.Path, probeTiming(healthcheck.File.ProbeTimingConfig)) {
+			return false
+		}
+		log.Printf("Healthcheck status for service %s on the file %s ok", s.Id, healthcheck.File.Path)
+	}
+
+	if len(healthcheck.Cmd.Cmd) > 0 {
+		if !cmdrunr.WaitForCmd(ctx, s.BasePath, s.Config.Path, healthcheck.Cmd.Cmd, s.serviceEnv(), probeTiming(healthcheck.Cmd.ProbeTimingConfig)) {
+			return false
+		}
+		log.Printf("Healthcheck status for service %s with the command \"%s\" ok", s.Id, healthcheck.Cmd.Cmd)
+	}
+
+	return true
+}
+
+func probeTiming(config cfg.ProbeTimingConfig) cmdrunr.ProbeTiming {
+	return cmdrunr.ProbeTiming{Interval: config.Interval, Timeout: config.Timeout}
+}
+
+// Returns the restart policy of the service, with the default values for the fields that are not configured
+func (s *ManagedService) restartPolicy() cfg.RestartPolicyConfig {
+	policy := s.Config.RestartPolicy
+	if policy.InitialDelay == 0 {
+		policy.InitialDelay = DEFAULT_RESTART_INITIAL_DELAY
+	}
+	if policy.Multiplier == 0 {
+		policy.Multiplier = DEFAULT_RESTART_MULTIPLIER
+	}
+	if policy.MaxDelay == 0 {
+		policy.MaxDelay = DEFAULT_RESTART_MAX_DELAY
+	}
+	if policy.MaxRestarts == 0 {
+		policy.MaxRestarts = DEFAULT_RESTART_MAX_COUNT
+	}
+	if policy.Window == 0 {
+		policy.Window = DEFAULT_RESTART_WINDOW
+	}
+
+	return policy
+}
+
+// Calculates the delay before a restart, given the number of restarts that already happened within the window
+func restartDelay(policy cfg.RestartPolicyConfig, previousRestarts int) time.Duration {
+	delay := float64(policy.InitialDelay) * math.Pow(policy.Multiplier, float64(previousRestarts))
+	if delay > float64(policy.MaxDelay) {
+		return policy.MaxDelay
+	}
+
+	return time.Duration(delay)
+}
+
+// Puts the crashed service in backoff and restarts it once the delay is over,
+// or marks it as crash-looping if it was restarted too many times within the window.
+// Be careful, you should lock the service's StateMtx before calling this method
+func (s *ManagedService) scheduleRestart(baseCtx context.Context) {
+	policy := s.restartPolicy()
+
+	// Forget about the restarts that are out of the window
+	now := time.Now()
+	s.restartHistory = slices.DeleteFunc(s.restartHistory, func(restartTime time.Time) bool {
+		return now.Sub(restartTime) > policy.Window
+	})
+
+	if len(s.restartHistory) >= policy.MaxRestarts {
+		log.Printf("The service %s is crash-looping, it will not be restarted", s.Id)
+		_, _ = fmt.Fprintf(&s.Output, "\nThe service crashed %d times in less than %s, it will not be restarted anymore\n\n", len(s.restartHistory)+1, policy.Window)
+		s.State = SERVICE_CRASH_LOOP
+		return
+	}
+
+	delay := restartDelay(policy, len(s.restartHistory))
+	s.restartHistory = append(s.restartHistory, now)
+	s.RestartCount++
+
+	log.Printf("The service %s crashed, restarting it in %s", s.Id, delay)
+	_, _ = fmt.Fprintf(&s.Output, "\nThe service crashed, restarting it in %s (restart #%d)\n\n", delay, s.RestartCount)
+
+	// The backoff has its own context, so that a kill or a shutdown can interrupt the wait
+	ctx, cancel := context.WithCancelCause(baseCtx)
+	s.ctx, s.cancel = ctx, cancel
+	s.State = SERVICE_BACKOFF
+
+	go func() {
+		// The dependents that must restart with the service are stopped during the backoff
+		cascade := s.cascadingDependents()
+		for _, dependent := range cascade {
+			dependent.Kill(true)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+
+		s.StateMtx.Lock()
+		if ctx.Err() != nil {
 			s.State = SERVICE_OFF
-		} else {
-			s.State = SERVICE_ERROR
+			s.StartupOverCond.Broadcast()
+			s.DoneCond.Broadcast()
+			s.StateMtx.Unlock()
+			return
 		}
 
-		s.DoneCond.Broadcast()
+		cancel(nil)
+		s.launch(baseCtx)
+		s.StateMtx.Unlock()
+
+		outputWidth, outputHeight := s.OutputSize.Get()
+		s.startCascade(baseCtx, cascade, outputWidth, outputHeight)
 	}()
 }
 
@@ -326,6 +694,18 @@ func (s *ManagedService) onStartupSuccess() {
 	s.StartupOverCond.Broadcast()
 }
 
+// Marks the service as unhealthy if it was in the "starting" phase, so that its dependents are not started
+func (s *ManagedService) onStartupFailure() {
+	s.StateMtx.Lock()
+	defer s.StateMtx.Unlock()
+
+	if s.State == SERVICE_STARTING {
+		s.State = SERVICE_UNHEALTHY
+	}
+
+	s.StartupOverCond.Broadcast()
+}
+
 // Trigger the kill message, then waits for the process to have finished (and if appOnly=false, same for all dependencies)
 func (s *ManagedService) Kill(appOnly bool) {
 	s.StateMtx.Lock()
@@ -333,15 +713,34 @@ func (s *ManagedService) Kill(appOnly bool) {
 		s.StateMtx.Unlock()
 		return
 	}
-	s.StateMtx.Unlock()
 
-	s.cancel(cmdrunr.ErrPlannedKill)
+	// The stop_cmd runs without the lock, the "stopping" flag prevents an automatic restart in the meantime.
+	// A service in backoff has no process to stop.
+	ranStopCmd := false
+	if len(s.Config.StopCmd) > 0 && s.State != SERVICE_BACKOFF && !s.stopping {
+		s.stopping = true
+		ranStopCmd = true
+		s.StateMtx.Unlock()
+
+		s.runStopCmd()
+
+		s.StateMtx.Lock()
+		s.waitForExit(s.stopTimeout())
+	}
+
+	// The signal is only the fallback of the stop_cmd, for a service that did not exit by itself.
+	// The cancel is done while holding the lock, so that it cannot target a context replaced by an automatic restart.
+	if s.IsInExecution() {
+		s.cancel(cmdrunr.ErrPlannedKill)
+	}
 
 	// Wait for the service to be done and broadcast it
-	s.StateMtx.Lock()
 	for s.IsInExecution() {
 		s.DoneCond.Wait()
 	}
+	if ranStopCmd {
+		s.stopping = false
+	}
 	s.StateMtx.Unlock()
 
 	if !appOnly {
@@ -353,10 +752,139 @@ func (s *ManagedService) Kill(appOnly bool) {
 	}
 }
 
-// Kill the service properly, then run the start sequence
+// Returns the duration given to the service to exit after its stop_cmd, which is also its grace period after the stop signal
+func (s *ManagedService) stopTimeout() time.Duration {
+	if s.Config.StopTimeout > 0 {
+		return s.Config.StopTimeout
+	}
+
+	return cmdrunr.DEFAULT_STOP_TIMEOUT
+}
+
+// Waits for the service to be done, for at most the given duration.
+// Be careful, you should lock the service's StateMtx before calling this method
+func (s *ManagedService) waitForExit(timeout time.Duration) {
+	timedOut := false
+	timer := time.AfterFunc(timeout, func() {
+		s.StateMtx.Lock()
+		timedOut = true
+		s.DoneCond.Broadcast()
+		s.StateMtx.Unlock()
+	})
+	defer timer.Stop()
+
+	for s.IsInExecution() && !timedOut {
+		s.DoneCond.Wait()
+	}
+}
+
+// Runs the stop_cmd of the service, with its output appended to the one of the service
+func (s *ManagedService) runStopCmd() {
+	log.Printf("Running the stop command of the service %s", s.Id)
+
+	ctx, cancel := context.WithTimeout(context.Background(), STOP_CMD_TIMEOUT)
+	defer cancel()
+
+	stopConfig := cfg.CmdConfig{Cmd: s.Config.StopCmd, Path: s.Config.Path}
+	if !cmdrunr.RunCommandInTerminal(ctx, s.BasePath, stopConfig, s.serviceEnv(), &s.Output, s.OutputSize) {
+		log.Printf("The stop command of the service %s failed", s.Id)
+		_, _ = fmt.Fprintf(&s.Output, "\nThe stop command failed, the service is killed\n")
+	}
+}
+
+// Kill the service properly, then run the start sequence.
+// The dependents declared with restart_with_target are stopped before the service, and started again once it is up.
 func (s *ManagedService) Restart(baseCtx context.Context, appOnly bool, outputWidth, outputHeight int) {
+	cascade := s.cascadingDependents()
+	for _, dependent := range cascade {
+		dependent.Kill(true)
+	}
+
 	s.Kill(appOnly)
 	s.Start(baseCtx, outputWidth, outputHeight)
+
+	s.startCascade(baseCtx, cascade, outputWidth, outputHeight)
+}
+
+// Returns the services in execution that must restart along with this one, because they declared
+// restart_with_target on it (directly or transitively).
+// The list is in stopping order: every service comes before the services it depends on, through any dependency.
+func (s *ManagedService) cascadingDependents() []*ManagedService {
+	visited := make(map[string]bool)
+	cascade := make([]*ManagedService, 0)
+
+	var visit func(service *ManagedService)
+	visit = func(service *ManagedService) {
+		for _, dependent := range service.cascadeDependents {
+			if visited[dependent.Id] {
+				continue
+			}
+			visited[dependent.Id] = true
+
+			visit(dependent)
+
+			dependent.StateMtx.Lock()
+			if dependent.IsInExecution() {
+				cascade = append(cascade, dependent)
+			}
+			dependent.StateMtx.Unlock()
+		}
+	}
+	visit(s)
+
+	// The order of the cascade follows the whole dependency graph, and not only the restart_with_target edges.
+	// Each service is added after all its dependencies, then the list is reversed.
+	inCascade := make(map[string]bool)
+	for _, service := range cascade {
+		inCascade[service.Id] = true
+	}
+	sorted := make([]*ManagedService, 0, len(cascade))
+	sortedIds := make(map[string]bool)
+
+	var sortDependencies func(service *ManagedService)
+	sortDependencies = func(service *ManagedService) {
+		if sortedIds[service.Id] {
+			return
+		}
+		sortedIds[service.Id] = true
+
+		for _, dependency := range service.Dependencies {
+			sortDependencies(dependency.Target)
+		}
+		if inCascade[service.Id] {
+			sorted = append(sorted, service)
+		}
+	}
+	for _, service := range cascade {
+		sortDependencies(service)
+	}
+	slices.Reverse(sorted)
+
+	return sorted
+}
+
+// Waits for the service to be done starting, then starts again the dependents that were stopped with it,
+// in the reverse order of the cascade. Nothing is started if the service failed.
+func (s *ManagedService) startCascade(baseCtx context.Context, cascade []*ManagedService, outputWidth, outputHeight int) {
+	if len(cascade) == 0 {
+		return
+	}
+
+	s.StateMtx.Lock()
+	for s.State == SERVICE_STARTING || s.State == SERVICE_BACKOFF {
+		s.StartupOverCond.Wait()
+	}
+	isRunning := s.State == SERVICE_RUNNING
+	s.StateMtx.Unlock()
+
+	if !isRunning {
+		log.Printf("The service %s did not start, its dependents will not be restarted", s.Id)
+		return
+	}
+
+	for _, dependent := range slices.Backward(cascade) {
+		dependent.Start(baseCtx, outputWidth, outputHeight)
+	}
 }
 
 // Run the os-specific command to open the service target
@@ -1,7 +1,12 @@
 package servicemgmt
 
 import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/corentindeboisset/tera/pkg/cfg"
 	"github.com/stretchr/testify/require"
@@ -29,7 +34,7 @@ services:
 `
 	config, err := cfg.ParseConfig([]byte(configText))
 	require.Nil(t, err)
-	orchestrator, err := NewOrchestrator(".", config.Services)
+	orchestrator, err := NewOrchestrator(".", config.EnvConfig, config.Services)
 	require.Nil(t, err)
 	require.Len(t, orchestrator.ServiceList, 3)
 	require.Equal(t, orchestrator.ServiceList["serviceC"].Id, "serviceC")
@@ -51,7 +56,7 @@ services:
 `
 	config, err := cfg.ParseConfig([]byte(autodependencyError))
 	require.Nil(t, err)
-	_, err = NewOrchestrator(".", config.Services)
+	_, err = NewOrchestrator(".", config.EnvConfig, config.Services)
 	require.ErrorContains(t, err, "the service \"serviceA\" cannot be dependent on itself")
 
 	invalidDependencyError := `
@@ -64,7 +69,7 @@ services:
 `
 	config, err = cfg.ParseConfig([]byte(invalidDependencyError))
 	require.Nil(t, err)
-	_, err = NewOrchestrator(".", config.Services)
+	_, err = NewOrchestrator(".", config.EnvConfig, config.Services)
 	require.ErrorContains(t, err, "the dependency target \"zozo\" of the service \"serviceA\" does not exist")
 
 	doubleDependencyError := `
@@ -82,7 +87,7 @@ services:
 `
 	config, err = cfg.ParseConfig([]byte(doubleDependencyError))
 	require.Nil(t, err)
-	_, err = NewOrchestrator(".", config.Services)
+	_, err = NewOrchestrator(".", config.EnvConfig, config.Services)
 	require.ErrorContains(t, err, "the service \"serviceA\" have two dependencies on \"serviceB\"")
 
 	circularDependencyError := `
@@ -107,6 +112,512 @@ services:
 `
 	config, err = cfg.ParseConfig([]byte(circularDependencyError))
 	require.Nil(t, err)
-	_, err = NewOrchestrator(".", config.Services)
+	_, err = NewOrchestrator(".", config.EnvConfig, config.Services)
 	require.ErrorContains(t, err, "a circular dependency have been detected between the services")
 }
+
+func TestRestartDelay(t *testing.T) {
+	t.Parallel()
+
+	policy := cfg.RestartPolicyConfig{
+		InitialDelay: time.Second,
+		Multiplier:   2,
+		MaxDelay:     5 * time.Second,
+	}
+
+	require.Equal(t, time.Second, restartDelay(policy, 0))
+	require.Equal(t, 2*time.Second, restartDelay(policy, 1))
+	require.Equal(t, 4*time.Second, restartDelay(policy, 2))
+	require.Equal(t, 5*time.Second, restartDelay(policy, 3))
+}
+
+func TestAutoRestartCrashLoop(t *testing.T) {
+	t.Parallel()
+
+	configText := `
+services:
+  crashing:
+    name: Crashing service
+    cmd: exit 1
+    auto_restart: true
+    restart_policy:
+      initial_delay: 10ms
+      max_restarts: 2
+      window: 1m
+`
+	config, err := cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+	orchestrator, err := NewOrchestrator(".", config.EnvConfig, config.Services)
+	require.Nil(t, err)
+
+	service := orchestrator.ServiceList["crashing"]
+	orchestrator.StartService("crashing", 80, 24)
+
+	require.Eventually(t, func() bool {
+		service.StateMtx.Lock()
+		defer service.StateMtx.Unlock()
+		return service.State == SERVICE_CRASH_LOOP
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, 2, service.RestartCount)
+
+	orchestrator.Shutdown(nil)
+}
+
+func TestRestartCascade(t *testing.T) {
+	t.Parallel()
+
+	configText := `
+services:
+  gateway:
+    name: Gateway
+    cmd: sleep 60
+    dependencies:
+      - target: api
+        restart_with_target: true
+
+  api:
+    name: API
+    cmd: sleep 60
+    dependencies:
+      - target: auth
+        restart_with_target: true
+        wait_target_restarted: true
+
+  auth:
+    name: Auth
+    cmd: sleep 60
+`
+	config, err := cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+	orchestrator, err := NewOrchestrator(".", config.EnvConfig, config.Services)
+	require.Nil(t, err)
+
+	auth := orchestrator.ServiceList["auth"]
+	api := orchestrator.ServiceList["api"]
+	gateway := orchestrator.ServiceList["gateway"]
+
+	// Only the services in execution are part of the cascade
+	require.Len(t, auth.cascadingDependents(), 0)
+
+	orchestrator.StartService("gateway", 80, 24)
+	isRunning := func(service *ManagedService) func() bool {
+		return func() bool {
+			service.StateMtx.Lock()
+			defer service.StateMtx.Unlock()
+			return service.State == SERVICE_RUNNING
+		}
+	}
+	require.Eventually(t, isRunning(gateway), 5*time.Second, 10*time.Millisecond)
+
+	require.Equal(t, []*ManagedService{gateway, api}, auth.cascadingDependents())
+
+	orchestrator.RestartService("auth", true, 80, 24)
+	require.Eventually(t, isRunning(api), 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, isRunning(gateway), 5*time.Second, 10*time.Millisecond)
+	require.Contains(t, string(api.Output.Bytes()), "Service killed")
+	require.Contains(t, string(gateway.Output.Bytes()), "Service killed")
+
+	orchestrator.Shutdown(nil)
+}
+
+func TestRestartCascadeOrder(t *testing.T) {
+	t.Parallel()
+
+	// The api and the worker restart with the auth, and the api also depends on the worker
+	basePath := t.TempDir()
+	configText := `
+services:
+  api:
+    name: API
+    cmd: echo "api" >> started && sleep 60
+    stop_cmd: echo "api" >> stopped
+    stop_timeout: 100ms
+    dependencies:
+      - target: auth
+        restart_with_target: true
+      - target: worker
+        wait_target_restarted: true
+
+  worker:
+    name: Worker
+    cmd: echo "worker" >> started && touch ready && sleep 60
+    run_before:
+      - cmd: rm -f ready
+    stop_cmd: echo "worker" >> stopped
+    stop_timeout: 100ms
+    healthcheck:
+      file:
+        path: ready
+        interval: 10ms
+    dependencies:
+      - target: auth
+        restart_with_target: true
+
+  auth:
+    name: Auth
+    cmd: sleep 60
+`
+	config, err := cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+	orchestrator, err := NewOrchestrator(basePath, config.EnvConfig, config.Services)
+	require.Nil(t, err)
+
+	auth := orchestrator.ServiceList["auth"]
+	api := orchestrator.ServiceList["api"]
+	worker := orchestrator.ServiceList["worker"]
+
+	orchestrator.StartService("api", 80, 24)
+	isRunning := func(service *ManagedService) func() bool {
+		return func() bool {
+			service.StateMtx.Lock()
+			defer service.StateMtx.Unlock()
+			return service.State == SERVICE_RUNNING
+		}
+	}
+	require.Eventually(t, isRunning(api), 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []*ManagedService{api, worker}, auth.cascadingDependents())
+	require.Nil(t, os.Remove(filepath.Join(basePath, "started")))
+
+	// The api is stopped before the worker it depends on, and started after it
+	orchestrator.RestartService("auth", true, 80, 24)
+	require.Eventually(t, isRunning(api), 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		started, err := os.ReadFile(filepath.Join(basePath, "started"))
+		return err == nil && len(strings.Fields(string(started))) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stopped, err := os.ReadFile(filepath.Join(basePath, "stopped"))
+	require.Nil(t, err)
+	require.Equal(t, []string{"api", "worker"}, strings.Fields(string(stopped)))
+	started, err := os.ReadFile(filepath.Join(basePath, "started"))
+	require.Nil(t, err)
+	require.Equal(t, []string{"worker", "api"}, strings.Fields(string(started)))
+
+	orchestrator.Shutdown(nil)
+}
+
+func TestFailedDependency(t *testing.T) {
+	t.Parallel()
+
+	configText := `
+services:
+  api:
+    name: API
+    cmd: sleep 60
+    dependencies:
+      - target: database
+        wait_target_restarted: true
+
+  database:
+    name: Database
+    cmd: exit 1
+    healthcheck:
+      port: 65000
+`
+	config, err := cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+	orchestrator, err := NewOrchestrator(".", config.EnvConfig, config.Services)
+	require.Nil(t, err)
+
+	api := orchestrator.ServiceList["api"]
+	orchestrator.StartService("api", 80, 24)
+
+	api.StateMtx.Lock()
+	require.Equal(t, SERVICE_FAILED_DEPENDENCY, api.State)
+	api.StateMtx.Unlock()
+	require.Contains(t, string(api.Output.Bytes()), "its dependency \"Database\" failed to start")
+
+	orchestrator.Shutdown(nil)
+
+	// A dependency that fails its healthcheck keeps running, but it is not ready
+	configText = `
+services:
+  api:
+    name: API
+    cmd: sleep 60
+    dependencies:
+      - target: database
+        wait_target_restarted: true
+
+  database:
+    name: Database
+    cmd: sleep 60
+    healthcheck:
+      file:
+        path: ready
+        interval: 10ms
+        timeout: 100ms
+`
+	config, err = cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+	orchestrator, err = NewOrchestrator(t.TempDir(), config.EnvConfig, config.Services)
+	require.Nil(t, err)
+
+	api = orchestrator.ServiceList["api"]
+	database := orchestrator.ServiceList["database"]
+	orchestrator.StartService("api", 80, 24)
+
+	api.StateMtx.Lock()
+	require.Equal(t, SERVICE_FAILED_DEPENDENCY, api.State)
+	api.StateMtx.Unlock()
+	database.StateMtx.Lock()
+	require.Equal(t, SERVICE_UNHEALTHY, database.State)
+	database.StateMtx.Unlock()
+	require.Contains(t, string(database.Output.Bytes()), "The healthcheck failed, the service is not ready")
+
+	orchestrator.Shutdown(nil)
+	database.StateMtx.Lock()
+	require.Equal(t, SERVICE_OFF, database.State)
+	database.StateMtx.Unlock()
+}
+
+func TestOutputHealthcheck(t *testing.T) {
+	t.Parallel()
+
+	configText := `
+services:
+  watcher:
+    name: Watcher
+    cmd: echo "Compiling..."; sleep 0.3; echo "Compiled successfully"; sleep 60
+    healthcheck:
+      output_regex: "^Compiled successfully$"
+`
+	config, err := cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+	orchestrator, err := NewOrchestrator(".", config.EnvConfig, config.Services)
+	require.Nil(t, err)
+
+	watcher := orchestrator.ServiceList["watcher"]
+	orchestrator.StartService("watcher", 80, 24)
+
+	watcher.StateMtx.Lock()
+	require.Equal(t, SERVICE_STARTING, watcher.State)
+	watcher.StateMtx.Unlock()
+
+	require.Eventually(t, func() bool {
+		watcher.StateMtx.Lock()
+		defer watcher.StateMtx.Unlock()
+		return watcher.State == SERVICE_RUNNING
+	}, 5*time.Second, 10*time.Millisecond)
+
+	orchestrator.Shutdown(nil)
+}
+
+func TestLivenessProbe(t *testing.T) {
+	t.Parallel()
+
+	basePath := t.TempDir()
+	configText := `
+services:
+  api:
+    name: API
+    cmd: touch alive && sleep 60
+    healthcheck:
+      file:
+        path: alive
+        interval: 10ms
+      liveness:
+        enabled: true
+        interval: 20ms
+        failure_threshold: 2
+`
+	config, err := cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+	orchestrator, err := NewOrchestrator(basePath, config.EnvConfig, config.Services)
+	require.Nil(t, err)
+
+	api := orchestrator.ServiceList["api"]
+	hasState := func(state ServiceState) func() bool {
+		return func() bool {
+			api.StateMtx.Lock()
+			defer api.StateMtx.Unlock()
+			return api.State == state && api.LastProbe != nil
+		}
+	}
+
+	orchestrator.StartService("api", 80, 24)
+	require.Eventually(t, hasState(SERVICE_RUNNING), 5*time.Second, 10*time.Millisecond)
+
+	require.Nil(t, os.Remove(filepath.Join(basePath, "alive")))
+	require.Eventually(t, hasState(SERVICE_UNHEALTHY), 5*time.Second, 10*time.Millisecond)
+	api.StateMtx.Lock()
+	require.False(t, api.LastProbe.Ok)
+	require.GreaterOrEqual(t, api.LastProbe.ConsecutiveFailures, 2)
+	api.StateMtx.Unlock()
+
+	require.Nil(t, os.WriteFile(filepath.Join(basePath, "alive"), nil, 0644))
+	require.Eventually(t, hasState(SERVICE_RUNNING), 5*time.Second, 10*time.Millisecond)
+
+	orchestrator.Shutdown(nil)
+}
+
+func TestStopCmd(t *testing.T) {
+	t.Parallel()
+
+	basePath := t.TempDir()
+	configText := `
+services:
+  database:
+    name: Database
+    cmd: trap 'echo "signalled"; exit 0' TERM; while [ ! -f stopped ]; do sleep 0.05; done; echo "exiting"; exit 3
+    stop_cmd: sleep 0.2 && echo "stopping the database" && touch stopped
+    auto_restart: true
+`
+	config, err := cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+	orchestrator, err := NewOrchestrator(basePath, config.EnvConfig, config.Services)
+	require.Nil(t, err)
+
+	database := orchestrator.ServiceList["database"]
+	orchestrator.StartService("database", 80, 24)
+	require.Eventually(t, func() bool {
+		return strings.Contains(string(database.Output.Bytes()), "> trap")
+	}, 5*time.Second, 10*time.Millisecond)
+	orchestrator.KillService("database", true)
+
+	// The service exited by itself after the stop command, it is not restarted
+	database.StateMtx.Lock()
+	require.Equal(t, SERVICE_OFF, database.State)
+	require.Equal(t, 0, database.RestartCount)
+	database.StateMtx.Unlock()
+
+	// It was given the time to exit, without receiving the stop signal
+	output := string(database.Output.Bytes())
+	require.Contains(t, output, "stopping the database")
+	require.Contains(t, output, "exiting")
+	require.NotContains(t, output, "signalled\n")
+
+	orchestrator.Shutdown(nil)
+}
+
+func TestServiceHooks(t *testing.T) {
+	t.Parallel()
+
+	basePath := t.TempDir()
+	configText := `
+services:
+  api:
+    name: API
+    cmd: cat migrated && sleep 60
+    run_before:
+      - cmd: echo "migration done" > migrated
+    run_after:
+      - cmd: echo "cleaning up"
+  broken:
+    name: Broken
+    cmd: echo "should not run"
+    run_before:
+      - cmd: exit 1
+`
+	config, err := cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+	orchestrator, err := NewOrchestrator(basePath, config.EnvConfig, config.Services)
+	require.Nil(t, err)
+
+	api := orchestrator.ServiceList["api"]
+	orchestrator.StartService("api", 80, 24)
+	// Wait for the command itself to print the file, since the header of the hook contains the text too
+	require.Eventually(t, func() bool {
+		return strings.Contains(string(api.Output.Bytes()), "> cat migrated && sleep 60\nmigration done")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The run_after hooks are run once the service is killed
+	orchestrator.KillService("api", true)
+	require.Contains(t, string(api.Output.Bytes()), "cleaning up")
+
+	// A failing run_before hook prevents the service from starting
+	broken := orchestrator.ServiceList["broken"]
+	orchestrator.StartService("broken", 80, 24)
+	require.Eventually(t, func() bool {
+		broken.StateMtx.Lock()
+		defer broken.StateMtx.Unlock()
+		return broken.State == SERVICE_ERROR
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NotContains(t, string(broken.Output.Bytes()), "should not run")
+	require.Contains(t, string(broken.Output.Bytes()), "A run_before hook failed")
+
+	orchestrator.Shutdown(nil)
+}
+
+func TestShutdownOrder(t *testing.T) {
+	t.Parallel()
+
+	basePath := t.TempDir()
+	configText := `
+services:
+  database:
+    name: Database
+    cmd: sleep 60
+    stop_cmd: echo "database" >> stopped
+    stop_timeout: 100ms
+  cache:
+    name: Cache
+    cmd: sleep 60
+    stop_cmd: sleep 0.2 && echo "cache" >> stopped
+    stop_timeout: 100ms
+  api:
+    name: API
+    cmd: sleep 60
+    stop_cmd: sleep 0.2 && echo "api" >> stopped
+    stop_timeout: 100ms
+    dependencies:
+      - target: database
+      - target: cache
+  worker:
+    name: Worker
+    cmd: sleep 60
+    stop_cmd: echo "worker" >> stopped
+    stop_timeout: 100ms
+    dependencies:
+      - target: api
+`
+	config, err := cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+	orchestrator, err := NewOrchestrator(basePath, config.EnvConfig, config.Services)
+	require.Nil(t, err)
+
+	orchestrator.StartService("worker", 80, 24)
+	orchestrator.Shutdown(nil)
+
+	// The dependents are stopped before their dependencies, whatever the duration of their stop_cmd
+	stopped, err := os.ReadFile(filepath.Join(basePath, "stopped"))
+	require.Nil(t, err)
+	lines := strings.Fields(string(stopped))
+	require.Len(t, lines, 4)
+	require.Equal(t, []string{"worker", "api"}, lines[:2])
+	require.ElementsMatch(t, []string{"database", "cache"}, lines[2:])
+}
+
+func TestServiceTerminalResize(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("the pseudo-terminals are only supported on Linux")
+	}
+
+	configText := `
+services:
+  webpack:
+    name: Webpack
+    cmd: echo ready; while [ "$(stty size)" != '30 100' ]; do sleep 0.05; done; echo resized; sleep 60
+    tty: true
+`
+	config, err := cfg.ParseConfig([]byte(configText))
+	require.Nil(t, err)
+	orchestrator, err := NewOrchestrator(t.TempDir(), config.EnvConfig, config.Services)
+	require.Nil(t, err)
+
+	webpack := orchestrator.ServiceList["webpack"]
+	orchestrator.StartService("webpack", 80, 24)
+	require.Eventually(t, func() bool {
+		return strings.Contains(string(webpack.Output.Bytes()), "\nready\n")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The terminal of the service follows the size of the output panel
+	orchestrator.ResizeOutputs(100, 30)
+	require.Eventually(t, func() bool {
+		return strings.Contains(string(webpack.Output.Bytes()), "\nresized\n")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	orchestrator.Shutdown(nil)
+}
@@ -18,7 +18,7 @@ func StartServiceManagement(confPath string) error {
 
 	cfg.SetupLogs(config.LogFilePath)
 
-	orchestrator, err := NewOrchestrator(config.BasePath, config.Services)
+	orchestrator, err := NewOrchestrator(config.BasePath, config.EnvConfig, config.Services)
 	if err != nil {
 		return err
 	}
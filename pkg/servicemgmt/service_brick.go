@@ -1,6 +1,9 @@
 package servicemgmt
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/charmbracelet/lipgloss"
 	"github.com/corentindeboisset/tera/pkg/iface"
 )
@@ -8,12 +11,16 @@ import (
 const BRICK_MIN_WIDTH = 25
 
 // It is important that all indicators have the same len
-const INDICATOR_LEN = 12
+const INDICATOR_LEN = 13
 const (
-	INDICATOR_OFF      = "⠺      OFF ⠗"
-	INDICATOR_STARTING = "⠺ STARTING ⠗"
-	INDICATOR_RUNNING  = "⠺  RUNNING ⠗"
-	INDICATOR_ERROR    = "⠺    ERROR ⠗"
+	INDICATOR_OFF       = "⠺       OFF ⠗"
+	INDICATOR_STARTING  = "⠺  STARTING ⠗"
+	INDICATOR_RUNNING   = "⠺   RUNNING ⠗"
+	INDICATOR_ERROR     = "⠺     ERROR ⠗"
+	INDICATOR_BACKOFF   = "⠺   BACKOFF ⠗"
+	INDICATOR_CRASHED   = "⠺   CRASHED ⠗"
+	INDICATOR_DEP_FAIL  = "⠺  DEP FAIL ⠗"
+	INDICATOR_UNHEALTHY = "⠺ UNHEALTHY ⠗"
 )
 
 const HPADDING = 2
@@ -30,12 +37,17 @@ type ServiceBrickModel struct {
 
 	brickStyle lipgloss.Style
 	titleStyle lipgloss.Style
+	probeStyle lipgloss.Style
 
 	// Status indicator styles
 	offStatusStyle      lipgloss.Style
 	startingStatusStyle lipgloss.Style
 	runningStatusStyle  lipgloss.Style
 	errorStatusStyle    lipgloss.Style
+	backoffStatusStyle  lipgloss.Style
+	crashedStatusStyle  lipgloss.Style
+	depFailStatusStyle  lipgloss.Style
+	unhealthyStyle      lipgloss.Style
 }
 
 func NewServiceBrick(id string, service *ManagedService, theme iface.Theme, width int) *ServiceBrickModel {
@@ -79,12 +91,21 @@ func (s *ServiceBrickModel) refreshStyles() {
 		MaxHeight(1).
 		Width(s.width - INDICATOR_LEN - HPADDING*2)
 
+	s.probeStyle = brickStyle.
+		Faint(true).
+		MaxHeight(1).
+		Width(max(s.width-HPADDING*2, 0))
+
 	s.brickStyle = brickStyle.Padding(1, 2)
 
 	s.offStatusStyle = brickStyle
 	s.startingStatusStyle = brickStyle.Foreground(lipgloss.Color("#d3a825"))
 	s.runningStatusStyle = brickStyle.Foreground(lipgloss.Color("#1eaa25"))
 	s.errorStatusStyle = brickStyle.Foreground(lipgloss.Color("#d82525"))
+	s.backoffStatusStyle = brickStyle.Foreground(lipgloss.Color("#d37a25"))
+	s.crashedStatusStyle = brickStyle.Foreground(lipgloss.Color("#d82525")).Bold(true)
+	s.depFailStatusStyle = brickStyle.Foreground(lipgloss.Color("#c2477f"))
+	s.unhealthyStyle = brickStyle.Foreground(lipgloss.Color("#d35a25"))
 }
 
 func (s *ServiceBrickModel) refreshCachedHeight() {
@@ -107,9 +128,19 @@ func (s *ServiceBrickModel) View() string {
 			Render("")
 	}
 
-	title := s.titleStyle.Render(s.service.Config.Name)
+	// The state is updated by the goroutines of the service, it is read at once under its lock
+	s.service.StateMtx.Lock()
+	state, restartCount, lastProbe := s.service.State, s.service.RestartCount, s.service.LastProbe
+	s.service.StateMtx.Unlock()
+
+	name := s.service.Config.Name
+	if restartCount > 0 {
+		name = fmt.Sprintf("%s ↻%d", name, restartCount)
+	}
+
+	title := s.titleStyle.Render(name)
 	var indicator string
-	switch s.service.State {
+	switch state {
 	case SERVICE_OFF:
 		indicator = s.offStatusStyle.Render(INDICATOR_OFF)
 	case SERVICE_STARTING:
@@ -118,12 +149,36 @@ func (s *ServiceBrickModel) View() string {
 		indicator = s.runningStatusStyle.Render(INDICATOR_RUNNING)
 	case SERVICE_ERROR:
 		indicator = s.errorStatusStyle.Render(INDICATOR_ERROR)
+	case SERVICE_BACKOFF:
+		indicator = s.backoffStatusStyle.Render(INDICATOR_BACKOFF)
+	case SERVICE_CRASH_LOOP:
+		indicator = s.crashedStatusStyle.Render(INDICATOR_CRASHED)
+	case SERVICE_FAILED_DEPENDENCY:
+		indicator = s.depFailStatusStyle.Render(INDICATOR_DEP_FAIL)
+	case SERVICE_UNHEALTHY:
+		indicator = s.unhealthyStyle.Render(INDICATOR_UNHEALTHY)
 	}
 	content := lipgloss.JoinHorizontal(lipgloss.Top, title, indicator)
 
+	// The line of the liveness probe is always displayed when it is enabled, so that the height of the brick is stable
+	if s.service.Config.Healthcheck.Liveness.Enabled {
+		content = lipgloss.JoinVertical(lipgloss.Left, content, s.probeStyle.Render(probeSummary(lastProbe)))
+	}
+
 	return s.brickStyle.Render(content)
 }
 
+func probeSummary(probe *ProbeResult) string {
+	if probe == nil {
+		return "♥ liveness: no probe yet"
+	}
+	if probe.Ok {
+		return fmt.Sprintf("♥ liveness: ok in %s", probe.Latency.Round(time.Millisecond))
+	}
+
+	return fmt.Sprintf("♥ liveness: failed (%d in a row) in %s", probe.ConsecutiveFailures, probe.Latency.Round(time.Millisecond))
+}
+
 func (s *ServiceBrickModel) Id() string {
 	return s.id
 }
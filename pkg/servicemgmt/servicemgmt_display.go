@@ -134,6 +134,9 @@ func (m *ifaceModel) refreshLayoutSizes() {
 
 	m.serviceListPanel.Resize(m.serviceListPanelWidth, panelsHeight)
 	m.outputPanel.Resize(m.width-m.serviceListPanelWidth, panelsHeight)
+
+	// The commands running in a pseudo-terminal follow the size of the output panel
+	m.orchestrator.ResizeOutputs(m.outputPanel.InnerFrameWidth(), m.outputPanel.InnerFrameHeight())
 }
 
 func (m *ifaceModel) initializeServiceList() {